@@ -0,0 +1,647 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	client "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned"
+	factory "github.com/starizard/kube-envoy-controller/pkg/client/informers/externalversions"
+	"github.com/starizard/kube-envoy-controller/pkg/config"
+	envoyutils "github.com/starizard/kube-envoy-controller/pkg/envoy"
+	"github.com/starizard/kube-envoy-controller/pkg/leader"
+	"github.com/starizard/kube-envoy-controller/pkg/logging"
+	"github.com/starizard/kube-envoy-controller/pkg/metrics"
+	"github.com/starizard/kube-envoy-controller/pkg/xds"
+)
+
+// controller reconciles envoys. It only talks to the cluster through the clients, informers and
+// recorder it is given, so the generated fake clientsets and record.FakeRecorder can stand in for them.
+type controller struct {
+	clientset     client.Interface
+	kubeclientset kubernetes.Interface
+	// a pair of informer factories per watched namespace, or a single pair under metav1.NamespaceAll,
+	// so that a controller limited to some namespaces needs no cluster wide RBAC
+	sharedFactories map[string]factory.SharedInformerFactory
+	kubeFactories   map[string]kubeinformers.SharedInformerFactory
+	queue           workqueue.RateLimitingInterface
+	// recorder records events on envoys. Only the leader records, followers do not write to the cluster.
+	recorder  record.EventRecorder
+	xdsServer *xds.Server
+	// elector is nil when leader election is disabled
+	elector *leader.Elector
+	// connections counts the xds streams open on the other replicas, nil without leader election
+	connections *leader.Connections
+
+	// workers reconcile different envoys in parallel, an envoy that failed maxRetries times is given up on
+	workers    int
+	maxRetries int
+	// workerTimeout is how long a worker may spend on a single key before liveness fails
+	workerTimeout time.Duration
+	// busySince holds per worker the unix nano time it picked up its current key, 0 while it waits
+	busySince []int64
+}
+
+// newController returns a controller for the namespaces, selector, workers and retries of cfg.
+// Its informers are not started and no event handlers are registered yet.
+func newController(cfg config.Config, clientset client.Interface, kubeclientset kubernetes.Interface, recorder record.EventRecorder) *controller {
+	sharedFactories, kubeFactories := newFactories(cfg, clientset, kubeclientset)
+	c := &controller{
+		clientset:       clientset,
+		kubeclientset:   kubeclientset,
+		sharedFactories: sharedFactories,
+		kubeFactories:   kubeFactories,
+		// the queue is named so that pkg/metrics reports its depth, latency and retries
+		queue:         workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(cfg.RetryBaseDelay.Duration, cfg.RetryMaxDelay.Duration), "envoys"),
+		recorder:      recorder,
+		xdsServer:     xds.NewServer(),
+		workers:       cfg.Workers,
+		maxRetries:    cfg.MaxRetries,
+		workerTimeout: cfg.Health.WorkerTimeout.Duration,
+		busySince:     make([]int64, cfg.Workers),
+	}
+	// node ids are envoy keys, so a proxy (dis)connecting refreshes the XDSConnected condition
+	c.xdsServer.OnConnectionChange = func(nodeID string) {
+		c.queue.Add(nodeID)
+		if c.connections != nil {
+			c.connections.Changed()
+		}
+	}
+	return c
+}
+
+// xdsConnected counts the proxies of nodeID connected to any replica
+func (c *controller) xdsConnected(nodeID string) int {
+	connected := c.xdsServer.Connected(nodeID)
+	if c.connections != nil {
+		connected += c.connections.Count(nodeID)
+	}
+	return connected
+}
+
+// addEventHandlers registers the informers of a watched namespace and returns what their caches synced
+func (c *controller) addEventHandlers(namespace string) []cache.InformerSynced {
+	informer := c.sharedFactoryFor(namespace).Envoy().V1().Envoys().Informer()
+	routeInformer := c.sharedFactoryFor(namespace).Envoy().V1().EnvoyRoutes().Informer()
+	listenerInformer := c.sharedFactoryFor(namespace).Envoy().V1().EnvoyListeners().Informer()
+	svcInformer := c.kubeFactoryFor(namespace).Core().V1().Services().Informer()
+	epInformer := c.kubeFactoryFor(namespace).Core().V1().Endpoints().Informer()
+	deploymentInformer := c.kubeFactoryFor(namespace).Apps().V1().Deployments().Informer()
+	cfgInformer := c.kubeFactoryFor(namespace).Core().V1().ConfigMaps().Informer()
+
+	// Add informer event handlers to respond to changes in the resource, we can enqueue the new changes to the workqueue
+	informer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				c.enqueue(obj)
+
+			},
+			UpdateFunc: func(old interface{}, cur interface{}) {
+				// status is written by the controller itself, it does not need another sync
+				if envoyChanged(old.(*v1.Envoy), cur.(*v1.Envoy)) {
+					c.enqueue(cur)
+
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				c.enqueue(obj)
+			},
+		},
+	)
+
+	// changes to generated objects, from rollouts to someone deleting the service, resync their envoy
+	ownerHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueOwner,
+		UpdateFunc: func(old interface{}, cur interface{}) {
+			if !reflect.DeepEqual(old, cur) {
+				c.enqueueOwner(cur)
+			}
+		},
+		DeleteFunc: c.enqueueOwner,
+	}
+	deploymentInformer.AddEventHandler(ownerHandler)
+	svcInformer.AddEventHandler(ownerHandler)
+	cfgInformer.AddEventHandler(ownerHandler)
+
+	// services, endpoints and routes feed the xds resources of builtIn fleets in the same namespace
+	fleetHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueFleets,
+		UpdateFunc: func(old interface{}, cur interface{}) {
+			if !reflect.DeepEqual(old, cur) {
+				c.enqueueFleets(cur)
+			}
+		},
+		DeleteFunc: c.enqueueFleets,
+	}
+	svcInformer.AddEventHandler(fleetHandler)
+	epInformer.AddEventHandler(fleetHandler)
+	routeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueFleets,
+		UpdateFunc: func(old interface{}, cur interface{}) {
+			// status is written while reconciling envoys, only spec and label changes matter here
+			oldRoute, curRoute := old.(*v1.EnvoyRoute), cur.(*v1.EnvoyRoute)
+			if !reflect.DeepEqual(oldRoute.Spec, curRoute.Spec) || !reflect.DeepEqual(oldRoute.Labels, curRoute.Labels) {
+				c.enqueueFleets(cur)
+			}
+		},
+		DeleteFunc: c.enqueueFleets,
+	})
+	// listeners change the ports of every envoy they select, not only builtIn ones
+	listenerInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueEnvoys,
+		UpdateFunc: func(old interface{}, cur interface{}) {
+			if !reflect.DeepEqual(old, cur) {
+				c.enqueueEnvoys(cur)
+			}
+		},
+		DeleteFunc: c.enqueueEnvoys,
+	})
+
+	return []cache.InformerSynced{informer.HasSynced, routeInformer.HasSynced, listenerInformer.HasSynced,
+		svcInformer.HasSynced, epInformer.HasSynced, deploymentInformer.HasSynced, cfgInformer.HasSynced}
+}
+
+// isLeader reports whether this replica may write to the cluster
+func (c *controller) isLeader() bool {
+	return c.elector == nil || c.elector.IsLeader()
+}
+
+// enqueueAll enqueues every envoy, e.g. when this replica starts writing to the cluster
+func (c *controller) enqueueAll() {
+	for _, f := range c.sharedFactories {
+		envoys, err := f.Envoy().V1().Envoys().Lister().List(labels.Everything())
+		if err != nil {
+			slog.Error("Error listing envoys", "err", err)
+			return
+		}
+		for _, envoy := range envoys {
+			c.enqueue(envoy)
+		}
+	}
+}
+
+// work runs the configured number of workers until the queue is shut down
+func (c *controller) work() {
+	slog.Info("Starting workers", "workers", c.workers)
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for c.processNextItem(worker) {
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (c *controller) processNextItem(worker int) bool {
+	key, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(key)
+	defer c.busy(worker)()
+	// once shutting down only the reconciles already in flight finish, queued keys are left for the next start
+	if c.queue.ShuttingDown() {
+		return false
+	}
+
+	strKey, ok := key.(string)
+	if !ok {
+		slog.Error("Invalid key format", "key", key)
+		c.queue.Forget(key)
+		return true
+	}
+	// every message of this sync carries the reconcile id and the envoy's namespace, name and, once read, generation
+	namespace, name, _ := cache.SplitMetaNamespaceKey(strKey)
+	ctx := logging.NewContext(context.Background(), slog.With("reconcileID", logging.NewID(), "namespace", namespace, "name", name))
+	start := time.Now()
+	err := c.processItem(ctx, strKey)
+	metrics.ObserveReconcile(start, err)
+	c.handleErr(ctx, strKey, err)
+	logging.FromContext(ctx).Debug("Synced", "duration", time.Since(start))
+	return true
+}
+
+// handleErr forgets a key once it synced, and otherwise retries it with the queue's exponential
+// backoff until maxRetries, after which the envoy is marked degraded and left alone until it changes
+func (c *controller) handleErr(ctx context.Context, key string, err error) {
+	logger := logging.FromContext(ctx)
+	if err == nil {
+		c.queue.Forget(key)
+		return
+	}
+	if retries := c.queue.NumRequeues(key); retries < c.maxRetries {
+		logger.Error("Error syncing, retrying", "err", err, "retries", retries)
+		c.queue.AddRateLimited(key)
+		return
+	}
+	c.queue.Forget(key)
+	logger.Error("Giving up", "err", err, "retries", c.maxRetries)
+	if err := c.markRetriesExhausted(key, err); err != nil {
+		logger.Error("Error recording failure", "err", err)
+	}
+}
+
+func (c *controller) markRetriesExhausted(key string, syncErr error) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	envoy, err := c.sharedFactoryFor(namespace).Envoy().V1().Envoys().Lister().Envoys(namespace).Get(name)
+	if errors.IsNotFound(err) || !c.isLeader() {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	message := fmt.Sprintf("gave up after %d retries: %v", c.maxRetries, syncErr)
+	c.recordEvent(envoy, apiv1.EventTypeWarning, reasonRetriesExhausted, "%s", message)
+	status := envoy.Status.DeepCopy()
+	status.Conditions = envoyutils.SetCondition(status.Conditions, v1.EnvoyDegraded, apiv1.ConditionTrue, reasonRetriesExhausted, message)
+	return envoyutils.UpdateStatus(c.clientset, envoy, *status)
+}
+
+// processItem syncs the envoy behind key; a returned error requeues the key
+func (c *controller) processItem(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error splitting key into parts", "err", err)
+		return nil
+	}
+	// keys also come from the node ids of xds clients, which may name envoys this controller does not watch
+	if !c.watched(namespace) {
+		return nil
+	}
+
+	//retrieve the object
+	obj, err := c.sharedFactoryFor(namespace).Envoy().V1().Envoys().Lister().Envoys(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		// generated objects are garbage collected, only the xds snapshot is left to drop
+		c.xdsServer.ClearResources(key)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("getting object %s %s: %v", namespace, name, err)
+	}
+	ctx = logging.With(ctx, "generation", obj.Generation)
+
+	// envoys created while the defaulting webhook was not installed are reconciled like defaulted ones
+	envoy := obj.DeepCopy()
+	defaulted := envoyutils.Default(envoy)
+
+	if !c.isLeader() {
+		return c.serveXDS(ctx, envoy)
+	}
+	if envoy.DeletionTimestamp != nil {
+		return c.finalize(ctx, envoy)
+	}
+	if defaulted {
+		// the spec change bumps the generation and brings the envoy back to reconcile
+		logging.FromContext(ctx).Info("Writing defaults")
+		if _, err := c.clientset.EnvoyV1().Envoys(namespace).Update(envoy); err != nil {
+			return fmt.Errorf("writing defaults of %s: %v", key, err)
+		}
+		return nil
+	}
+
+	//Reconcile expected state with current state
+	return c.reconcile(ctx, envoy)
+}
+
+func (c *controller) reconcile(ctx context.Context, envoy *v1.Envoy) error {
+	envoy, err := c.syncFinalizer(envoy)
+	if err != nil {
+		return err
+	}
+	observed, err := c.syncEnvoy(ctx, envoy)
+	status := envoyutils.Status(envoy, observed)
+	c.recordSync(envoy, observed, status)
+	if statusErr := envoyutils.UpdateStatus(c.clientset, envoy, status); statusErr != nil && err == nil {
+		err = statusErr
+	}
+	return err
+}
+
+// syncEnvoy brings the generated objects and the xds snapshot of envoy in line with its spec.
+// The returned observation feeds the envoy status, even when the sync failed halfway.
+func (c *controller) syncEnvoy(ctx context.Context, envoy *v1.Envoy) (envoyutils.Observed, error) {
+	observed := envoyutils.Observed{XDSConnected: c.xdsConnected(envoyutils.NodeID(envoy))}
+	listeners, err := c.selectedListeners(ctx, envoy)
+	if err != nil {
+		observed.SyncErr = err
+		return observed, err
+	}
+
+	if err := c.syncConfigMap(ctx, envoy); err != nil {
+		observed.ConfigErr = err
+		return observed, err
+	}
+	if observed.Deployment, err = c.syncDeployment(ctx, envoy, listeners); err != nil {
+		observed.SyncErr = err
+		return observed, err
+	}
+	if err := c.syncXDS(ctx, envoy, listeners); err != nil {
+		observed.SyncErr = err
+		return observed, err
+	}
+	if observed.Service, err = c.syncService(ctx, envoy, listeners); err != nil {
+		observed.SyncErr = err
+		return observed, err
+	}
+	if err := c.pruneRenamed(ctx, envoy); err != nil {
+		observed.SyncErr = err
+		return observed, err
+	}
+	return observed, nil
+}
+
+// syncXDS publishes the resources of a builtIn fleet, or drops them when the fleet left builtIn xds.
+// Routes are only reported accepted once the snapshot serving them is published.
+func (c *controller) syncXDS(ctx context.Context, envoy *v1.Envoy, listeners []*v1.EnvoyListener) error {
+	if !envoy.Spec.XDS.BuiltIn {
+		c.xdsServer.ClearResources(envoyutils.NodeID(envoy))
+		return c.updateRouteStatus(envoy, nil, nil)
+	}
+	resources, routes, err := c.xdsResources(ctx, envoy, listeners)
+	if err != nil {
+		return err
+	}
+	if err := c.xdsServer.SetResources(envoyutils.NodeID(envoy), resources); err != nil {
+		return err
+	}
+	return c.updateRouteStatus(envoy, routes.selected, routes.rejected)
+}
+
+// serveXDS is all a replica that does not hold the lease does: keep serving the snapshot of a
+// builtIn fleet up to date without writing to the cluster
+func (c *controller) serveXDS(ctx context.Context, envoy *v1.Envoy) error {
+	if !envoy.Spec.XDS.BuiltIn || envoy.DeletionTimestamp != nil {
+		c.xdsServer.ClearResources(envoyutils.NodeID(envoy))
+		return nil
+	}
+	listeners, err := c.selectedListeners(ctx, envoy)
+	if err != nil {
+		return err
+	}
+	resources, _, err := c.xdsResources(ctx, envoy, listeners)
+	if err != nil {
+		return err
+	}
+	return c.xdsServer.SetResources(envoyutils.NodeID(envoy), resources)
+}
+
+// routeStatus is which routes select a fleet and why some of them are not served to it
+type routeStatus struct {
+	selected []*v1.EnvoyRoute
+	rejected map[string]error
+}
+
+// xdsResources builds the clusters, endpoints, routes and listeners served to a builtIn xds fleet,
+// and the status of the routes selecting it
+func (c *controller) xdsResources(ctx context.Context, envoy *v1.Envoy, listeners []*v1.EnvoyListener) (xds.Resources, routeStatus, error) {
+	services, err := c.exposedServices(envoy)
+	if err != nil {
+		return xds.Resources{}, routeStatus{}, err
+	}
+	endpoints := map[string]*apiv1.Endpoints{}
+	for _, svc := range services {
+		eps, err := c.kubeFactoryFor(envoy.Namespace).Core().V1().Endpoints().Lister().Endpoints(envoy.Namespace).Get(svc.Name)
+		if err == nil {
+			endpoints[svc.Name] = eps
+		}
+	}
+	resources := xds.ServiceResources(services, endpoints)
+
+	var rejectedListeners map[string]error
+	resources.Listeners, rejectedListeners = xds.ListenerResources(listeners, services)
+	for name, err := range rejectedListeners {
+		logging.FromContext(ctx).Warn("Listener not served", "listener", name, "err", err)
+	}
+
+	routes, err := c.selectedRoutes(ctx, envoy)
+	if err != nil {
+		return xds.Resources{}, routeStatus{}, err
+	}
+	status := routeStatus{selected: routes, rejected: map[string]error{}}
+	// a snapshot holding a route configuration that no listener fetches is inconsistent and rejected
+	if !servesHTTP(listeners, rejectedListeners) {
+		for _, r := range routes {
+			status.rejected[r.Name] = fmt.Errorf("envoy %s has no HTTP listener", envoy.Name)
+		}
+		return resources, status, nil
+	}
+	routeConfig, rejected := xds.RouteResources(routes, services)
+	resources.Routes = []types.Resource{routeConfig}
+	status.rejected = rejected
+	return resources, status, nil
+}
+
+// servesHTTP reports whether an HTTP listener, which fetches xds.RouteConfigName over RDS, is served
+func servesHTTP(listeners []*v1.EnvoyListener, rejected map[string]error) bool {
+	for _, l := range listeners {
+		if l.Spec.Protocol == v1.ListenerHTTP && rejected[l.Name] == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *controller) exposedServices(envoy *v1.Envoy) ([]*apiv1.Service, error) {
+	if envoy.Spec.ServiceSelector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(envoy.Spec.ServiceSelector)
+	if err != nil {
+		return nil, err
+	}
+	return c.kubeFactoryFor(envoy.Namespace).Core().V1().Services().Lister().Services(envoy.Namespace).List(selector)
+}
+
+// selectedRoutes returns the envoy routes selecting envoy, oldest first so earlier routes keep their domains
+func (c *controller) selectedRoutes(ctx context.Context, envoy *v1.Envoy) ([]*v1.EnvoyRoute, error) {
+	all, err := c.sharedFactoryFor(envoy.Namespace).Envoy().V1().EnvoyRoutes().Lister().EnvoyRoutes(envoy.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var routes []*v1.EnvoyRoute
+	for _, r := range all {
+		if selectsEnvoy(ctx, r, r.Spec.EnvoySelector, envoy) {
+			routes = append(routes, r)
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool { return olderFirst(routes[i], routes[j]) })
+	return routes, nil
+}
+
+// selectedListeners returns the listeners declared on envoy followed by the envoy listeners
+// selecting it, oldest first. A listener whose container or service port is already taken by one
+// before it is skipped.
+func (c *controller) selectedListeners(ctx context.Context, envoy *v1.Envoy) ([]*v1.EnvoyListener, error) {
+	all, err := c.sharedFactoryFor(envoy.Namespace).Envoy().V1().EnvoyListeners().Lister().EnvoyListeners(envoy.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(all, func(i, j int) bool { return olderFirst(all[i], all[j]) })
+	inline := envoyutils.InlineListeners(envoy)
+	all = append(inline, all...)
+
+	var listeners []*v1.EnvoyListener
+	ports, servicePorts := map[int32]string{}, map[int32]string{}
+	for i, l := range all {
+		if i >= len(inline) && !selectsEnvoy(ctx, l, l.Spec.EnvoySelector, envoy) {
+			continue
+		}
+		servicePort := envoyutils.ListenerServicePort(l)
+		if owner, ok := ports[l.Spec.Port]; ok {
+			logging.FromContext(ctx).Warn("Listener skipped, port taken", "listener", l.Name, "port", l.Spec.Port, "takenBy", owner)
+			continue
+		}
+		if owner, ok := servicePorts[servicePort]; ok {
+			logging.FromContext(ctx).Warn("Listener skipped, service port taken", "listener", l.Name, "servicePort", servicePort, "takenBy", owner)
+			continue
+		}
+		ports[l.Spec.Port], servicePorts[servicePort] = l.Name, l.Name
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// selectsEnvoy reports whether the envoy selector of a route or listener matches envoy
+func selectsEnvoy(ctx context.Context, obj metav1.Object, envoySelector metav1.LabelSelector, envoy *v1.Envoy) bool {
+	selector, err := metav1.LabelSelectorAsSelector(&envoySelector)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Invalid envoy selector", "object", obj.GetName(), "err", err)
+		return false
+	}
+	return selector.Matches(labels.Set(envoy.Labels))
+}
+
+func olderFirst(a, b metav1.Object) bool {
+	ta, tb := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+	if !ta.Equal(&tb) {
+		return ta.Before(&tb)
+	}
+	return a.GetName() < b.GetName()
+}
+
+// updateRouteStatus records on every route in the namespace whether envoy serves it
+func (c *controller) updateRouteStatus(envoy *v1.Envoy, selected []*v1.EnvoyRoute, rejected map[string]error) error {
+	isSelected := map[string]bool{}
+	for _, r := range selected {
+		isSelected[r.Name] = true
+	}
+	all, err := c.sharedFactoryFor(envoy.Namespace).Envoy().V1().EnvoyRoutes().Lister().EnvoyRoutes(envoy.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, r := range all {
+		var statuses []v1.RouteEnvoyStatus
+		for _, s := range r.Status.Envoys {
+			if s.Name != envoy.Name {
+				statuses = append(statuses, s)
+			}
+		}
+		if isSelected[r.Name] {
+			status := v1.RouteEnvoyStatus{Name: envoy.Name, Accepted: true}
+			if err := rejected[r.Name]; err != nil {
+				status.Accepted = false
+				status.Reason = err.Error()
+			}
+			statuses = append(statuses, status)
+			sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+		}
+		if reflect.DeepEqual(statuses, r.Status.Envoys) {
+			continue
+		}
+		updated := r.DeepCopy()
+		updated.Status.Envoys = statuses
+		if _, err := c.clientset.EnvoyV1().EnvoyRoutes(r.Namespace).Update(updated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueueFleets enqueues every builtIn xds envoy in the namespace of a changed service, endpoints or route
+func (c *controller) enqueueFleets(obj interface{}) {
+	c.enqueueNamespace(obj, true)
+}
+
+// enqueueEnvoys enqueues every envoy in the namespace of a changed listener
+func (c *controller) enqueueEnvoys(obj interface{}) {
+	c.enqueueNamespace(obj, false)
+}
+
+func (c *controller) enqueueNamespace(obj interface{}, builtInOnly bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		slog.Error("Error reading object meta", "err", err)
+		return
+	}
+	envoys, err := c.sharedFactoryFor(meta.GetNamespace()).Envoy().V1().Envoys().Lister().Envoys(meta.GetNamespace()).List(labels.Everything())
+	if err != nil {
+		slog.Error("Error listing envoys", "err", err)
+		return
+	}
+	for _, envoy := range envoys {
+		if envoy.Spec.XDS.BuiltIn || !builtInOnly {
+			c.enqueue(envoy)
+		}
+	}
+}
+
+// enqueueOwner enqueues the envoy controlling a generated object
+func (c *controller) enqueueOwner(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		slog.Error("Error reading object meta", "err", err)
+		return
+	}
+	ref := metav1.GetControllerOf(meta)
+	if ref == nil || ref.Kind != "Envoy" || ref.APIVersion != v1.SchemeGroupVersion.String() {
+		return
+	}
+	c.queue.Add(meta.GetNamespace() + "/" + ref.Name)
+}
+
+func envoyChanged(old, cur *v1.Envoy) bool {
+	return old.Generation != cur.Generation ||
+		!reflect.DeepEqual(old.Labels, cur.Labels) ||
+		!reflect.DeepEqual(old.Finalizers, cur.Finalizers) ||
+		!reflect.DeepEqual(old.DeletionTimestamp, cur.DeletionTimestamp)
+}
+
+func (c *controller) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		slog.Error("Error obtaining key", "err", err)
+		return
+	}
+
+	c.queue.Add(key)
+}
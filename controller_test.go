@@ -0,0 +1,606 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/tools/reference"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	"github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/fake"
+	"github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/scheme"
+	"github.com/starizard/kube-envoy-controller/pkg/config"
+	envoyutils "github.com/starizard/kube-envoy-controller/pkg/envoy"
+	"github.com/starizard/kube-envoy-controller/pkg/leader"
+	"github.com/starizard/kube-envoy-controller/pkg/xds"
+)
+
+// the fake tracker guesses "envoies" from the kind, the generated fake client asks for "envoys"
+var envoysResource = schema.GroupVersionResource{Group: v1.SchemeGroupVersion.Group, Version: "v1", Resource: "envoys"}
+
+// fixture is a controller running against fake clientsets, whose informer caches are filled by hand
+type fixture struct {
+	t             *testing.T
+	clientset     *fake.Clientset
+	kubeclientset *kubefake.Clientset
+	recorder      *resolvingRecorder
+	c             *controller
+}
+
+// resolvingRecorder fails the test on events the recorder of newRecorder would drop because it
+// cannot resolve their object to a reference
+type resolvingRecorder struct {
+	t *testing.T
+	*record.FakeRecorder
+}
+
+func (r *resolvingRecorder) resolves(object runtime.Object) bool {
+	if _, err := reference.GetReference(scheme.Scheme, object); err != nil {
+		r.t.Errorf("event on %T would be dropped: %v", object, err)
+		return false
+	}
+	return true
+}
+
+func (r *resolvingRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.resolves(object) {
+		r.FakeRecorder.Event(object, eventtype, reason, message)
+	}
+}
+
+func (r *resolvingRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.resolves(object) {
+		r.FakeRecorder.Eventf(object, eventtype, reason, messageFmt, args...)
+	}
+}
+
+func (r *resolvingRecorder) PastEventf(object runtime.Object, timestamp metav1.Time, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.resolves(object) {
+		r.FakeRecorder.PastEventf(object, timestamp, eventtype, reason, messageFmt, args...)
+	}
+}
+
+func (r *resolvingRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.resolves(object) {
+		r.FakeRecorder.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+	}
+}
+
+func newFixture(t *testing.T) *fixture {
+	f := &fixture{
+		t:             t,
+		clientset:     fake.NewSimpleClientset(),
+		kubeclientset: kubefake.NewSimpleClientset(),
+	}
+	f.recorder = &resolvingRecorder{t: t, FakeRecorder: record.NewFakeRecorder(100)}
+	cfg := config.Default()
+	cfg.MaxRetries = 2
+	f.c = newController(cfg, f.clientset, f.kubeclientset, f.recorder)
+	return f
+}
+
+// testEnvoy returns a defaulted envoy as the defaulting webhook stores it
+func testEnvoy(name string) *v1.Envoy {
+	envoy := &v1.Envoy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID("uid-" + name), Generation: 1},
+	}
+	envoyutils.Default(envoy)
+	return envoy
+}
+
+// addEnvoy puts envoy in the informer cache and the fake api server
+func (f *fixture) addEnvoy(envoy *v1.Envoy) {
+	f.t.Helper()
+	if err := f.c.sharedFactoryFor(envoy.Namespace).Envoy().V1().Envoys().Informer().GetIndexer().Add(envoy); err != nil {
+		f.t.Fatal(err)
+	}
+	if err := f.clientset.Tracker().Create(envoysResource, envoy, envoy.Namespace); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// addListener puts an envoy listener in the informer cache and the fake api server
+func (f *fixture) addListener(l *v1.EnvoyListener) {
+	f.t.Helper()
+	if err := f.c.sharedFactoryFor(l.Namespace).Envoy().V1().EnvoyListeners().Informer().GetIndexer().Add(l); err != nil {
+		f.t.Fatal(err)
+	}
+	if err := f.clientset.Tracker().Add(l); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// addRoute puts an envoy route in the informer cache and the fake api server
+func (f *fixture) addRoute(r *v1.EnvoyRoute) {
+	f.t.Helper()
+	if err := f.c.sharedFactoryFor(r.Namespace).Envoy().V1().EnvoyRoutes().Informer().GetIndexer().Add(r); err != nil {
+		f.t.Fatal(err)
+	}
+	if err := f.clientset.Tracker().Add(r); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// addKube puts a deployment, service or config map in its informer cache and the fake api server
+func (f *fixture) addKube(obj runtime.Object) {
+	f.t.Helper()
+	factory := f.c.kubeFactoryFor(metav1.NamespaceAll)
+	var err error
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		if err = factory.Apps().V1().Deployments().Informer().GetIndexer().Add(o); err == nil {
+			_, err = f.kubeclientset.AppsV1().Deployments(o.Namespace).Create(o)
+		}
+	case *apiv1.Service:
+		if err = factory.Core().V1().Services().Informer().GetIndexer().Add(o); err == nil {
+			_, err = f.kubeclientset.CoreV1().Services(o.Namespace).Create(o)
+		}
+	case *apiv1.ConfigMap:
+		if err = factory.Core().V1().ConfigMaps().Informer().GetIndexer().Add(o); err == nil {
+			_, err = f.kubeclientset.CoreV1().ConfigMaps(o.Namespace).Create(o)
+		}
+	default:
+		err = fmt.Errorf("unexpected %T", obj)
+	}
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	// only the controller's own requests are checked
+	f.kubeclientset.ClearActions()
+}
+
+// generated returns the objects the controller generates for envoy, as the api server stores them
+func (f *fixture) generated(envoy *v1.Envoy) (*apiv1.ConfigMap, *appsv1.Deployment, *apiv1.Service) {
+	f.t.Helper()
+	cfgMap, err := envoyutils.ConfigMap(envoy)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	cfgMap.Namespace = envoy.Namespace
+	deployment, err := envoyutils.Deployment(envoy, nil)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	deployment = serverDefaulted(deployment)
+	deployment.Namespace = envoy.Namespace
+	deployment.Spec.Replicas = envoy.Spec.Replicas
+	service := envoyutils.Service(envoy, nil)
+	service.Namespace = envoy.Namespace
+	service.Spec.ClusterIP = "10.0.0.10"
+	return cfgMap, deployment, service
+}
+
+func (f *fixture) sync(envoy *v1.Envoy) error {
+	key := envoy.Namespace + "/" + envoy.Name
+	err := f.c.processItem(context.Background(), key)
+	f.c.handleErr(context.Background(), key, err)
+	return err
+}
+
+// kubeActions returns the writes to the kube api of verb on resource
+func (f *fixture) kubeActions(verb, resource string) []core.Action {
+	var out []core.Action
+	for _, a := range f.kubeclientset.Actions() {
+		if a.GetVerb() == verb && a.GetResource().Resource == resource {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// envoyActions returns the requests to the envoy api of verb, on subresource if set
+func (f *fixture) envoyActions(verb, resource, subresource string) []core.Action {
+	var out []core.Action
+	for _, a := range f.clientset.Actions() {
+		if a.GetVerb() == verb && a.GetResource().Resource == resource && a.GetSubresource() == subresource {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// stored reads envoy back from the fake api server
+func (f *fixture) stored(envoy *v1.Envoy) *v1.Envoy {
+	f.t.Helper()
+	stored, err := f.clientset.EnvoyV1().Envoys(envoy.Namespace).Get(envoy.Name, metav1.GetOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return stored
+}
+
+// events drains the recorded events
+func (f *fixture) events() []string {
+	var out []string
+	for {
+		select {
+		case e := <-f.recorder.Events:
+			out = append(out, e)
+		default:
+			return out
+		}
+	}
+}
+
+func (f *fixture) expectEvent(want string) {
+	f.t.Helper()
+	events := f.events()
+	for _, e := range events {
+		if strings.HasPrefix(e, want) {
+			return
+		}
+	}
+	f.t.Fatalf("no event %q in %q", want, events)
+}
+
+func condition(status v1.EnvoyStatus, t v1.EnvoyConditionType) v1.EnvoyCondition {
+	for _, c := range status.Conditions {
+		if c.Type == t {
+			return c
+		}
+	}
+	return v1.EnvoyCondition{}
+}
+
+func TestSyncCreatesGeneratedObjects(t *testing.T) {
+	f := newFixture(t)
+	envoy := testEnvoy("edge")
+	f.addEnvoy(envoy)
+
+	if err := f.sync(envoy); err != nil {
+		t.Fatal(err)
+	}
+	for _, resource := range []string{"configmaps", "deployments", "services"} {
+		if n := len(f.kubeActions("create", resource)); n != 1 {
+			t.Errorf("%d %s created, want 1", n, resource)
+		}
+	}
+	stored := f.stored(envoy)
+	if !hasFinalizer(stored) {
+		t.Error("builtIn envoy has no finalizer")
+	}
+	if stored.Status.ObservedGeneration != 1 || stored.Status.DeploymentName != "edge" {
+		t.Errorf("status not written: %+v", stored.Status)
+	}
+	if c := condition(stored.Status, v1.EnvoyConfigRendered); c.Status != apiv1.ConditionTrue {
+		t.Errorf("ConfigRendered = %s, want True", c.Status)
+	}
+	if c := condition(stored.Status, v1.EnvoyXDSConnected); c.Reason != "NoProxies" {
+		t.Errorf("XDSConnected reason %s, want NoProxies", c.Reason)
+	}
+	// a fleet without an HTTP listener still gets a consistent snapshot
+	if _, ok := f.c.xdsServer.Versions()[envoyutils.NodeID(envoy)]; !ok {
+		t.Error("no xds snapshot published")
+	}
+	f.expectEvent("Normal Created Created configmap edge")
+}
+
+func TestSyncRecreatesMissingConfigMap(t *testing.T) {
+	f := newFixture(t)
+	envoy := testEnvoy("edge")
+	envoy.Finalizers = []string{finalizerName}
+	f.addEnvoy(envoy)
+	_, deployment, service := f.generated(envoy)
+	f.addKube(deployment)
+	f.addKube(service)
+
+	if err := f.sync(envoy); err != nil {
+		t.Fatal(err)
+	}
+	created := f.kubeActions("create", "configmaps")
+	if len(created) != 1 {
+		t.Fatalf("%d config maps created, want 1", len(created))
+	}
+	cfgMap := created[0].(core.CreateAction).GetObject().(*apiv1.ConfigMap)
+	if cfgMap.Name != envoy.Spec.ConfigMapName || cfgMap.Data["envoy.yaml"] == "" {
+		t.Fatalf("created %s without a bootstrap", cfgMap.Name)
+	}
+	if n := len(f.kubeActions("update", "deployments")) + len(f.kubeActions("update", "services")); n != 0 {
+		t.Fatalf("%d updates to objects in shape", n)
+	}
+	f.expectEvent("Normal Created Created configmap edge")
+}
+
+func TestSyncScalesDeployment(t *testing.T) {
+	f := newFixture(t)
+	envoy := testEnvoy("edge")
+	envoy.Finalizers = []string{finalizerName}
+	cfgMap, deployment, service := f.generated(envoy)
+	replicas := int32(3)
+	envoy.Spec.Replicas = &replicas
+	f.addEnvoy(envoy)
+	f.addKube(cfgMap)
+	f.addKube(deployment)
+	f.addKube(service)
+
+	if err := f.sync(envoy); err != nil {
+		t.Fatal(err)
+	}
+	updates := f.kubeActions("update", "deployments")
+	if len(updates) != 1 {
+		t.Fatalf("%d deployment updates, want 1", len(updates))
+	}
+	updated := updates[0].(core.UpdateAction).GetObject().(*appsv1.Deployment)
+	if *updated.Spec.Replicas != 3 {
+		t.Fatalf("deployment scaled to %d, want 3", *updated.Spec.Replicas)
+	}
+	// server defaulted fields are kept rather than fought over
+	if updated.Spec.Template.Spec.RestartPolicy != apiv1.RestartPolicyAlways {
+		t.Fatal("the update dropped fields the controller does not own")
+	}
+	f.expectEvent("Normal Updated Updated deployment edge")
+}
+
+func TestSyncReplacesDeploymentForNewSelector(t *testing.T) {
+	f := newFixture(t)
+	envoy := testEnvoy("edge")
+	envoy.Finalizers = []string{finalizerName}
+	cfgMap, deployment, service := f.generated(envoy)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "envoy"}}
+	f.addEnvoy(envoy)
+	f.addKube(cfgMap)
+	f.addKube(deployment)
+	f.addKube(service)
+
+	if err := f.sync(envoy); err != nil {
+		t.Fatal(err)
+	}
+	deletes := f.kubeActions("delete", "deployments")
+	if len(deletes) != 1 {
+		t.Fatalf("%d deployment deletes, want 1", len(deletes))
+	}
+	if n := len(f.kubeActions("update", "deployments")); n != 0 {
+		t.Fatalf("%d updates of an immutable selector", n)
+	}
+	f.expectEvent("Normal Deleted Deleted deployment edge leaving its pods running")
+}
+
+func TestFinalize(t *testing.T) {
+	for _, policy := range []v1.DeletionPolicy{v1.DeletionPolicyDelete, v1.DeletionPolicyOrphan} {
+		t.Run(string(policy), func(t *testing.T) {
+			f := newFixture(t)
+			envoy := testEnvoy("edge")
+			envoy.Spec.DeletionPolicy = policy
+			envoy.Finalizers = []string{finalizerName}
+			now := metav1.NewTime(time.Now())
+			envoy.DeletionTimestamp = &now
+			f.addEnvoy(envoy)
+			cfgMap, deployment, service := f.generated(envoy)
+			f.addKube(cfgMap)
+			f.addKube(deployment)
+			f.addKube(service)
+			if err := f.c.xdsServer.SetResources(envoyutils.NodeID(envoy), xds.Resources{}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := f.sync(envoy); err != nil {
+				t.Fatal(err)
+			}
+			if hasFinalizer(f.stored(envoy)) {
+				t.Fatal("finalizer not removed")
+			}
+			if _, ok := f.c.xdsServer.Versions()[envoyutils.NodeID(envoy)]; ok {
+				t.Fatal("xds snapshot of a deleted envoy still served")
+			}
+
+			updated := map[string]metav1.Object{}
+			for _, resource := range []string{"configmaps", "deployments", "services"} {
+				for _, a := range f.kubeActions("update", resource) {
+					obj := a.(core.UpdateAction).GetObject().(metav1.Object)
+					updated[resource] = obj
+				}
+			}
+			if policy == v1.DeletionPolicyDelete {
+				if len(updated) != 0 {
+					t.Fatalf("generated objects changed although they are garbage collected: %v", updated)
+				}
+				return
+			}
+			if len(updated) != 3 {
+				t.Fatalf("%d generated objects released, want 3", len(updated))
+			}
+			for resource, obj := range updated {
+				if envoyutils.IsOwnedBy(obj, envoy) {
+					t.Errorf("%s still owned by the envoy", resource)
+				}
+			}
+			f.expectEvent("Normal Orphaned")
+		})
+	}
+}
+
+func TestSyncErrorRequeues(t *testing.T) {
+	f := newFixture(t)
+	envoy := testEnvoy("edge")
+	envoy.Finalizers = []string{finalizerName}
+	f.addEnvoy(envoy)
+	f.kubeclientset.PrependReactor("create", "deployments", func(core.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("quota exceeded")
+	})
+	key := "default/edge"
+
+	if err := f.sync(envoy); err == nil {
+		t.Fatal("a failed create was not reported")
+	}
+	if n := f.c.queue.NumRequeues(key); n != 1 {
+		t.Fatalf("requeued %d times, want 1", n)
+	}
+	stored := f.stored(envoy)
+	if c := condition(stored.Status, v1.EnvoyDegraded); c.Status != apiv1.ConditionTrue || !strings.Contains(c.Message, "quota exceeded") {
+		t.Fatalf("Degraded = %s %q, want True with the error", c.Status, c.Message)
+	}
+	f.expectEvent("Warning SyncFailed Sync failed: quota exceeded")
+
+	// once the retries are used up the envoy is given up on until it changes
+	f.sync(envoy)
+	f.sync(envoy)
+	if n := f.c.queue.NumRequeues(key); n != 0 {
+		t.Fatalf("still retrying after %d requeues", n)
+	}
+	f.expectEvent("Warning RetriesExhausted")
+}
+
+func TestSyncReportsBadBootstrapOverrides(t *testing.T) {
+	f := newFixture(t)
+	envoy := testEnvoy("edge")
+	envoy.Finalizers = []string{finalizerName}
+	envoy.Spec.BootstrapOverrides = &runtime.RawExtension{Raw: []byte(`"admin"`)}
+	f.addEnvoy(envoy)
+
+	if err := f.sync(envoy); err == nil {
+		t.Fatal("an unrenderable bootstrap was not reported")
+	}
+	for _, resource := range []string{"configmaps", "deployments"} {
+		if n := len(f.kubeActions("create", resource)); n != 0 {
+			t.Errorf("%d %s created from an unrenderable bootstrap", n, resource)
+		}
+	}
+	stored := f.stored(envoy)
+	if c := condition(stored.Status, v1.EnvoyConfigRendered); c.Status != apiv1.ConditionFalse || !strings.Contains(c.Message, "bootstrapOverrides") {
+		t.Fatalf("ConfigRendered = %s %q, want False with the error", c.Status, c.Message)
+	}
+	f.expectEvent("Warning ConfigRenderFailed Rendering the bootstrap config map failed: applying bootstrapOverrides")
+}
+
+func TestStatusWriteErrorRequeues(t *testing.T) {
+	f := newFixture(t)
+	envoy := testEnvoy("edge")
+	envoy.Finalizers = []string{finalizerName}
+	f.addEnvoy(envoy)
+	f.clientset.PrependReactor("update", "envoys", func(action core.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() == "status" {
+			return true, nil, fmt.Errorf("conflict")
+		}
+		return false, nil, nil
+	})
+
+	if err := f.sync(envoy); err == nil {
+		t.Fatal("a failed status write was not reported")
+	}
+	if n := len(f.envoyActions("update", "envoys", "status")); n != 1 {
+		t.Fatalf("%d status writes, want 1", n)
+	}
+	if n := f.c.queue.NumRequeues("default/edge"); n != 1 {
+		t.Fatalf("requeued %d times, want 1", n)
+	}
+}
+
+func TestFollowerOnlyServesXDS(t *testing.T) {
+	f := newFixture(t)
+	f.c.elector = leader.NewElector(f.kubeclientset, leader.DefaultConfig())
+	envoy := testEnvoy("edge")
+	f.addEnvoy(envoy)
+
+	if err := f.sync(envoy); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.kubeclientset.Actions()); n != 0 {
+		t.Fatalf("follower wrote %d times to the kube api", n)
+	}
+	if n := len(f.envoyActions("update", "envoys", "")) + len(f.envoyActions("update", "envoys", "status")); n != 0 {
+		t.Fatalf("follower wrote %d times to envoys", n)
+	}
+	if _, ok := f.c.xdsServer.Versions()[envoyutils.NodeID(envoy)]; !ok {
+		t.Fatal("follower does not serve the xds snapshot")
+	}
+}
+
+func TestSyncRejectsRoutesWithoutHTTPListener(t *testing.T) {
+	f := newFixture(t)
+	envoy := testEnvoy("edge")
+	envoy.Labels = map[string]string{"fleet": "edge"}
+	envoy.Finalizers = []string{finalizerName}
+	f.addEnvoy(envoy)
+	fleet := metav1.LabelSelector{MatchLabels: map[string]string{"fleet": "edge"}}
+	f.addListener(&v1.EnvoyListener{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"},
+		Spec: v1.EnvoyListenerSpec{
+			EnvoySelector: fleet,
+			Port:          5432,
+			Protocol:      v1.ListenerTCP,
+			Backend:       &v1.RouteBackend{Service: "db", Port: intstr.FromInt(5432)},
+		},
+	})
+	f.addRoute(&v1.EnvoyRoute{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "api"},
+		Spec: v1.EnvoyRouteSpec{
+			EnvoySelector: fleet,
+			VirtualHosts: []v1.VirtualHost{{
+				Name:    "api",
+				Domains: []string{"*"},
+				Routes:  []v1.Route{{Match: v1.RouteMatch{Prefix: "/"}, Backends: []v1.RouteBackend{{Service: "api", Port: intstr.FromInt(80)}}}},
+			}},
+		},
+	})
+
+	if err := f.sync(envoy); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.c.xdsServer.Versions()[envoyutils.NodeID(envoy)]; !ok {
+		t.Fatal("no xds snapshot published for a fleet with a route and no HTTP listener")
+	}
+	route, err := f.clientset.EnvoyV1().EnvoyRoutes("default").Get("api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(route.Status.Envoys) != 1 || route.Status.Envoys[0].Accepted || !strings.Contains(route.Status.Envoys[0].Reason, "no HTTP listener") {
+		t.Fatalf("route status %+v, want rejected for lack of an HTTP listener", route.Status.Envoys)
+	}
+}
+
+func TestSyncOpensInlineListenersFirst(t *testing.T) {
+	f := newFixture(t)
+	envoy := testEnvoy("edge")
+	envoy.Labels = map[string]string{"fleet": "edge"}
+	envoy.Finalizers = []string{finalizerName}
+	envoy.Spec.Listeners = []v1.InlineListener{{Name: "web", Port: 8080, ServicePort: 80, Protocol: v1.ListenerHTTP}}
+	envoy.Spec.NodeSelector = map[string]string{"edge": "true"}
+	envoy.Spec.Tolerations = []apiv1.Toleration{{Key: "edge", Operator: apiv1.TolerationOpExists, Effect: apiv1.TaintEffectNoSchedule}}
+	f.addEnvoy(envoy)
+	fleet := metav1.LabelSelector{MatchLabels: map[string]string{"fleet": "edge"}}
+	backend := &v1.RouteBackend{Service: "db", Port: intstr.FromInt(5432)}
+	f.addListener(&v1.EnvoyListener{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "taken"},
+		Spec:       v1.EnvoyListenerSpec{EnvoySelector: fleet, Port: 8080, Protocol: v1.ListenerTCP, Backend: backend},
+	})
+	f.addListener(&v1.EnvoyListener{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"},
+		Spec:       v1.EnvoyListenerSpec{EnvoySelector: fleet, Port: 5432, Protocol: v1.ListenerTCP, Backend: backend},
+	})
+
+	if err := f.sync(envoy); err != nil {
+		t.Fatal(err)
+	}
+	creates := f.kubeActions("create", "deployments")
+	if len(creates) != 1 {
+		t.Fatalf("%d deployments created, want 1", len(creates))
+	}
+	pod := creates[0].(core.CreateAction).GetObject().(*appsv1.Deployment).Spec.Template.Spec
+	var ports []string
+	for _, p := range pod.Containers[0].Ports {
+		ports = append(ports, fmt.Sprintf("%s:%d", p.Name, p.ContainerPort))
+	}
+	if got := strings.Join(ports, ","); got != "http-8080:8080,tcp-5432:5432" {
+		t.Fatalf("container ports %s, want the inline listener's before the db listener's", got)
+	}
+	if pod.NodeSelector["edge"] != "true" || len(pod.Tolerations) != 1 {
+		t.Fatalf("pods not placed by the envoy: node selector %v, tolerations %v", pod.NodeSelector, pod.Tolerations)
+	}
+	service := f.kubeActions("create", "services")[0].(core.CreateAction).GetObject().(*apiv1.Service)
+	if service.Spec.Ports[0].Port != 80 {
+		t.Fatalf("inline listener exposed on %d, want its service port 80", service.Spec.Ports[0].Port)
+	}
+}
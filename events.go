@@ -0,0 +1,73 @@
+package main
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	"github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/scheme"
+	envoyutils "github.com/starizard/kube-envoy-controller/pkg/envoy"
+)
+
+// Reasons of the events recorded on envoys, shown by kubectl describe envoy
+const (
+	reasonCreated          = "Created"
+	reasonUpdated          = "Updated"
+	reasonDeleted          = "Deleted"
+	reasonOrphaned         = "Orphaned"
+	reasonConfigFailed     = "ConfigRenderFailed"
+	reasonSyncFailed       = "SyncFailed"
+	reasonRetriesExhausted = "RetriesExhausted"
+	reasonRolloutComplete  = "RolloutComplete"
+	reasonMigrated         = "Migrated"
+)
+
+// newRecorder returns a recorder writing events through kubeclientset. The broadcaster's correlator
+// aggregates similar events into one with a count and drops events of an envoy beyond a burst of 25,
+// refilled every 5 minutes, so an envoy failing in a hot loop does not flood etcd.
+func newRecorder(kubeclientset kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeclientset.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, apiv1.EventSource{Component: "kube-envoy-controller"})
+}
+
+// envoyReference is what events are recorded on. It is built here because objects read from the
+// informers or returned by the typed clients carry no kind, and api servers no longer set the self
+// link the recorder would fall back to.
+func envoyReference(envoy *v1.Envoy) *apiv1.ObjectReference {
+	return &apiv1.ObjectReference{
+		Kind:            "Envoy",
+		APIVersion:      v1.SchemeGroupVersion.String(),
+		Name:            envoy.Name,
+		Namespace:       envoy.Namespace,
+		UID:             envoy.UID,
+		ResourceVersion: envoy.ResourceVersion,
+	}
+}
+
+// recordEvent records an event on envoy
+func (c *controller) recordEvent(envoy *v1.Envoy, eventType, reason, messageFmt string, args ...interface{}) {
+	c.recorder.Eventf(envoyReference(envoy), eventType, reason, messageFmt, args...)
+}
+
+// recordChange records a successful create, update or delete of a generated object on its envoy
+func (c *controller) recordChange(envoy *v1.Envoy, reason, kind, name string, err error) {
+	if err == nil {
+		c.recordEvent(envoy, apiv1.EventTypeNormal, reason, "%s %s %s", reason, kind, name)
+	}
+}
+
+// recordSync records why a sync of envoy failed, and a rollout completed by it
+func (c *controller) recordSync(envoy *v1.Envoy, observed envoyutils.Observed, status v1.EnvoyStatus) {
+	switch {
+	case observed.ConfigErr != nil:
+		c.recordEvent(envoy, apiv1.EventTypeWarning, reasonConfigFailed, "Rendering the bootstrap config map failed: %v", observed.ConfigErr)
+	case observed.SyncErr != nil:
+		c.recordEvent(envoy, apiv1.EventTypeWarning, reasonSyncFailed, "Sync failed: %v", observed.SyncErr)
+	}
+	if envoy.Status.Rollout == v1.RolloutProgressing && status.Rollout == v1.RolloutComplete {
+		c.recordEvent(envoy, apiv1.EventTypeNormal, reasonRolloutComplete, "Deployment %s rolled out %d replicas", status.DeploymentName, status.UpdatedReplicas)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	envoyutils "github.com/starizard/kube-envoy-controller/pkg/envoy"
+	"github.com/starizard/kube-envoy-controller/pkg/logging"
+)
+
+// finalizerName holds an envoy back from deletion until the controller has cleaned up after it
+const finalizerName = "envoys.envoy.starizard.io/cleanup"
+
+// needsFinalizer reports whether deleting envoy takes more than garbage collecting its objects:
+// builtIn fleets have an xds snapshot and route statuses, orphaned fleets lose their owner references
+func needsFinalizer(envoy *v1.Envoy) bool {
+	return envoy.Spec.XDS.BuiltIn || envoy.Spec.DeletionPolicy == v1.DeletionPolicyOrphan
+}
+
+// syncFinalizer adds or removes the finalizer as the spec requires and returns the stored envoy
+func (c *controller) syncFinalizer(envoy *v1.Envoy) (*v1.Envoy, error) {
+	has := hasFinalizer(envoy)
+	if has == needsFinalizer(envoy) {
+		return envoy, nil
+	}
+	updated := envoy.DeepCopy()
+	if has {
+		updated.Finalizers = withoutFinalizer(updated.Finalizers)
+	} else {
+		updated.Finalizers = append(updated.Finalizers, finalizerName)
+	}
+	return c.clientset.EnvoyV1().Envoys(envoy.Namespace).Update(updated)
+}
+
+// finalize cleans up what garbage collection cannot and then releases the envoy
+func (c *controller) finalize(ctx context.Context, envoy *v1.Envoy) error {
+	if !hasFinalizer(envoy) {
+		return nil
+	}
+	c.xdsServer.ClearResources(envoyutils.NodeID(envoy))
+	if err := c.updateRouteStatus(envoy, nil, nil); err != nil {
+		return err
+	}
+	if envoy.Spec.DeletionPolicy == v1.DeletionPolicyOrphan {
+		if err := c.orphan(ctx, envoy); err != nil {
+			return err
+		}
+	}
+
+	updated := envoy.DeepCopy()
+	updated.Finalizers = withoutFinalizer(updated.Finalizers)
+	_, err := c.clientset.EnvoyV1().Envoys(envoy.Namespace).Update(updated)
+	return err
+}
+
+// orphan drops envoy's owner reference from its deployment, service and config map so they outlive it
+func (c *controller) orphan(ctx context.Context, envoy *v1.Envoy) error {
+	deploymentsClient := c.kubeclientset.AppsV1().Deployments(envoy.Namespace)
+	svcClient := c.kubeclientset.CoreV1().Services(envoy.Namespace)
+	cfgClient := c.kubeclientset.CoreV1().ConfigMaps(envoy.Namespace)
+
+	deployment, err := c.kubeFactoryFor(envoy.Namespace).Apps().V1().Deployments().Lister().Deployments(envoy.Namespace).Get(envoy.Spec.Name)
+	if err == nil && envoyutils.IsOwnedBy(deployment, envoy) {
+		deployment = deployment.DeepCopy()
+		deployment.OwnerReferences = withoutOwner(deployment.OwnerReferences, envoy)
+		_, err = deploymentsClient.Update(deployment)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	service, err := c.kubeFactoryFor(envoy.Namespace).Core().V1().Services().Lister().Services(envoy.Namespace).Get(envoy.Spec.Name)
+	if err == nil && envoyutils.IsOwnedBy(service, envoy) {
+		service = service.DeepCopy()
+		service.OwnerReferences = withoutOwner(service.OwnerReferences, envoy)
+		_, err = svcClient.Update(service)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	cfg, err := c.kubeFactoryFor(envoy.Namespace).Core().V1().ConfigMaps().Lister().ConfigMaps(envoy.Namespace).Get(envoy.Spec.ConfigMapName)
+	if err == nil && envoyutils.IsOwnedBy(cfg, envoy) {
+		cfg = cfg.DeepCopy()
+		cfg.OwnerReferences = withoutOwner(cfg.OwnerReferences, envoy)
+		_, err = cfgClient.Update(cfg)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	logging.FromContext(ctx).Info("Orphaned deployment, service and configmap")
+	c.recordEvent(envoy, apiv1.EventTypeNormal, reasonOrphaned, "Left deployment %s, service %s and configmap %s behind", envoy.Spec.Name, envoy.Spec.Name, envoy.Spec.ConfigMapName)
+	return nil
+}
+
+func hasFinalizer(envoy *v1.Envoy) bool {
+	for _, f := range envoy.Finalizers {
+		if f == finalizerName {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutFinalizer(finalizers []string) []string {
+	var out []string
+	for _, f := range finalizers {
+		if f != finalizerName {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func withoutOwner(refs []metav1.OwnerReference, envoy *v1.Envoy) []metav1.OwnerReference {
+	var out []metav1.OwnerReference
+	for _, ref := range refs {
+		if ref.UID != envoy.UID {
+			out = append(out, ref)
+		}
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log/slog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	client "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned"
+	factory "github.com/starizard/kube-envoy-controller/pkg/client/informers/externalversions"
+	"github.com/starizard/kube-envoy-controller/pkg/client/informers/externalversions/internalinterfaces"
+	"github.com/starizard/kube-envoy-controller/pkg/config"
+)
+
+// newFactories creates the informer factories for the watched namespaces. The label selector only
+// applies to envoys, routes and listeners, the objects they select or generate are not labelled by shard.
+func newFactories(cfg config.Config, clientset client.Interface, kubeclientset kubernetes.Interface) (map[string]factory.SharedInformerFactory, map[string]kubeinformers.SharedInformerFactory) {
+	sharedFactories := map[string]factory.SharedInformerFactory{}
+	kubeFactories := map[string]kubeinformers.SharedInformerFactory{}
+	namespaces := cfg.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	var tweak internalinterfaces.TweakListOptionsFunc
+	if cfg.LabelSelector != "" {
+		tweak = func(options *metav1.ListOptions) {
+			options.LabelSelector = cfg.LabelSelector
+		}
+	}
+	for _, namespace := range namespaces {
+		sharedFactories[namespace] = factory.NewSharedInformerFactoryWithOptions(clientset, cfg.Resync.Duration,
+			factory.WithNamespace(namespace), factory.WithTweakListOptions(tweak))
+		kubeFactories[namespace] = kubeinformers.NewSharedInformerFactoryWithOptions(kubeclientset, cfg.Resync.Duration,
+			kubeinformers.WithNamespace(namespace))
+	}
+	return sharedFactories, kubeFactories
+}
+
+// watched reports whether objects in namespace are seen by the controller
+func (c *controller) watched(namespace string) bool {
+	_, all := c.sharedFactories[metav1.NamespaceAll]
+	_, ok := c.sharedFactories[namespace]
+	return all || ok
+}
+
+// sharedFactoryFor returns the factory of envoys, routes and listeners in a watched namespace
+func (c *controller) sharedFactoryFor(namespace string) factory.SharedInformerFactory {
+	if f, ok := c.sharedFactories[namespace]; ok {
+		return f
+	}
+	return c.sharedFactories[metav1.NamespaceAll]
+}
+
+// kubeFactoryFor returns the factory of services, endpoints and generated objects in a watched namespace
+func (c *controller) kubeFactoryFor(namespace string) kubeinformers.SharedInformerFactory {
+	if f, ok := c.kubeFactories[namespace]; ok {
+		return f
+	}
+	return c.kubeFactories[metav1.NamespaceAll]
+}
+
+// startInformers starts every factory and waits for the caches registered with them to fill
+func (c *controller) startInformers(synced []cache.InformerSynced, stopCh <-chan struct{}) bool {
+	for namespace := range c.sharedFactories {
+		c.sharedFactories[namespace].Start(stopCh)
+		c.kubeFactories[namespace].Start(stopCh)
+	}
+	slog.Info("Informers started", "namespaces", len(c.sharedFactories))
+	return cache.WaitForCacheSync(stopCh, synced...)
+}
@@ -1,186 +1,281 @@
 package main
 
 import (
-	"log"
+	"context"
+	"crypto/tls"
+	"flag"
+	"log/slog"
 	"os"
-	"reflect"
+	"path/filepath"
 	"time"
 
-	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/workqueue"
 
-	v1 "github.com/starizard/kube-envoy-controller/pkg/api/example.com/v1"
 	client "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned"
-	factory "github.com/starizard/kube-envoy-controller/pkg/client/informers/externalversions"
+	"github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/scheme"
+	"github.com/starizard/kube-envoy-controller/pkg/config"
 	envoyutils "github.com/starizard/kube-envoy-controller/pkg/envoy"
+	"github.com/starizard/kube-envoy-controller/pkg/leader"
+	"github.com/starizard/kube-envoy-controller/pkg/logging"
+	"github.com/starizard/kube-envoy-controller/pkg/metrics"
+	"github.com/starizard/kube-envoy-controller/pkg/webhook"
+	"github.com/starizard/kube-envoy-controller/pkg/xds"
 )
 
 var (
-	queue         = workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Second*5, time.Minute))
-	clientset     client.Interface
-	kubeclientset kubernetes.Interface
-	stopCh        = make(chan struct{})
-	sharedFactory factory.SharedInformerFactory
+	// stopCh is closed on shutdown, see signalContext
+	stopCh     = make(chan struct{})
+	xdsAddress = ":18000"
+	// metrics are not served when metricsAddress is empty
+	metricsAddress = ":8080"
+	// the webhook is only served when a certificate is mounted under webhookCertDir
+	webhookAddress = ":8443"
+	webhookCertDir = "/etc/kube-envoy-controller/certs"
 )
 
-func getConfig() *rest.Config {
-	kubeconfig := ""
-	if kubeconfig == "" {
-		kubeconfig = os.Getenv("KUBECONFIG")
-	}
-
+func getConfig(cfg config.Config) *rest.Config {
 	var (
 		config *rest.Config
 		err    error
 	)
 
-	if kubeconfig != "" {
-		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if cfg.Kubeconfig != "" || cfg.Master != "" {
+		config, err = clientcmd.BuildConfigFromFlags(cfg.Master, cfg.Kubeconfig)
 	} else {
 		config, err = rest.InClusterConfig()
 	}
 	if err != nil {
-		log.Printf("error creating client: %v", err)
+		slog.Error("Error creating client", "err", err)
 		os.Exit(1)
 	}
 	return config
 }
 
-func createClientSet() *client.Clientset {
-	return client.NewForConfigOrDie(getConfig())
+func createClientSet(cfg config.Config) *client.Clientset {
+	return client.NewForConfigOrDie(getConfig(cfg))
+}
+
+func createKubeClientSet(cfg config.Config) *kubernetes.Clientset {
+	return kubernetes.NewForConfigOrDie(getConfig(cfg))
 }
 
-func createKubeClientSet() *kubernetes.Clientset {
-	return kubernetes.NewForConfigOrDie(getConfig())
+func createDynamicClient(cfg config.Config) dynamic.Interface {
+	return dynamic.NewForConfigOrDie(getConfig(cfg))
+}
+
+// loadConfig reads the controller config from the command line, the file it names and the environment,
+// and exits when it is invalid or only had to be printed
+func loadConfig() config.Config {
+	cfg, printConfig, err := config.Load(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		slog.Error("Error loading config", "err", err)
+		os.Exit(2)
+	}
+	if err := logging.Setup(cfg.Log.Format, cfg.Log.Level); err != nil {
+		slog.Error("Error setting up logging", "err", err)
+		os.Exit(2)
+	}
+	if printConfig {
+		out, err := cfg.YAML()
+		if err != nil {
+			slog.Error("Error printing config", "err", err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(out)
+		os.Exit(0)
+	}
+	return cfg
+}
+
+// applyConfig hands the settings the controller struct does not hold to the packages and globals they tune
+func applyConfig(cfg config.Config) {
+	shutdownTimeout = cfg.ShutdownTimeout.Duration
+	xdsAddress, webhookAddress, webhookCertDir = cfg.XDS.BindAddress, cfg.Webhook.BindAddress, cfg.Webhook.CertDir
+	metricsAddress = cfg.Metrics.BindAddress
+	healthAddress = cfg.Health.BindAddress
+	envoyutils.Image, envoyutils.AdminPort = cfg.Envoy.Image, cfg.Envoy.AdminPort
+	// envoys with spec.xds.builtIn reach the embedded xds server through this host
+	envoyutils.BuiltInXDS.Host = cfg.XDS.Host
+	webhook.InitImage = cfg.Webhook.SidecarInitImage
 }
 
 func main() {
-	clientset = createClientSet()
-	kubeclientset = createKubeClientSet()
-	sharedFactory = factory.NewSharedInformerFactory(clientset, time.Second*30)
-	informer := sharedFactory.Example().V1().Envoys().Informer()
-
-	// Add informer event handlers to respond to changes in the resource, we can enqueue the new changes to the workqueue
-	informer.AddEventHandler(
-		cache.ResourceEventHandlerFuncs{
-			AddFunc: func(obj interface{}) {
-				enqueue(obj)
-
-			},
-			UpdateFunc: func(old interface{}, cur interface{}) {
-				if !reflect.DeepEqual(old, cur) {
-					enqueue(cur)
-
-				}
-			},
-			DeleteFunc: func(obj interface{}) {
-				// enqueue(obj)
-			},
-		},
-	)
+	cfg := loadConfig()
+	applyConfig(cfg)
+	ctx, cancel := signalContext()
+	clientset := createClientSet(cfg)
+	kubeclientset := createKubeClientSet(cfg)
+	c := newController(cfg, clientset, kubeclientset, newRecorder(kubeclientset))
+	// created before the workers start, which must not take this replica for the leader in the meantime
+	if cfg.LeaderElection.Enabled {
+		c.elector = leader.NewElector(kubeclientset, leaderConfig(cfg.LeaderElection))
+		// proxies connect to any replica, the leader reports those of all of them
+		c.connections = leader.NewConnections(kubeclientset, c.elector, c.xdsServer.ConnectedNodes)
+		c.connections.OnChange = func(nodeID string) { c.queue.Add(nodeID) }
+	}
+	var synced []cache.InformerSynced
+	for namespace := range c.sharedFactories {
+		synced = append(synced, c.addEventHandlers(namespace)...)
+	}
 
-	// this starts all registered informers
-	sharedFactory.Start(stopCh)
-	log.Println("Informer Started..")
+	// injected sidecars of every namespace share one snapshot passing their pod's traffic through
+	sidecar, err := xds.SidecarResources()
+	if err == nil {
+		err = c.xdsServer.SetResources(xds.SidecarNodeID, sidecar)
+	}
+	if err != nil {
+		slog.Error("Error publishing the sidecar xds resources", "err", err)
+		os.Exit(1)
+	}
+	serve("xds", func() error { return c.xdsServer.Run(xdsAddress, stopCh) })
+	if c.connections != nil {
+		serve("xds connections", func() error {
+			c.connections.Run(stopCh)
+			return nil
+		})
+	}
+
+	runWebhooks(clientset, kubeclientset)
+	runMetrics(c)
+	runProbes(c, synced)
 
-	if !cache.WaitForCacheSync(stopCh, informer.HasSynced) {
-		log.Println(("Error waiting for informer cache to sync"))
+	// this starts all registered informers
+	if !c.startInformers(synced, stopCh) {
+		slog.Error("Error waiting for informer caches to sync")
+		os.Exit(shutdown(c, nil))
 	}
 
 	// Start controller loop
-	work()
-}
+	workersDone := make(chan struct{})
+	go func() {
+		c.work()
+		close(workersDone)
+	}()
 
-func work() {
-	for {
-		key, shutdown := queue.Get()
+	if cfg.Migration.Enabled {
+		m := newMigrator(cfg, createDynamicClient(cfg), clientset, kubeclientset, c.recorder)
+		go m.run(cfg.Resync.Duration, c.isLeader, stopCh)
+	}
 
-		if shutdown {
-			stopCh <- struct{}{}
-			return
-		}
-		var strKey string
-		var ok bool
-		if strKey, ok = key.(string); !ok {
-			log.Printf("\n Invalid key format %v", key)
-			return
-		}
-		processItem(strKey)
+	// without leader election a single replica runs without a lease
+	var code int
+	if !cfg.LeaderElection.Enabled {
+		<-ctx.Done()
+		code = shutdown(c, workersDone)
+	} else {
+		code = c.runLeaderElection(ctx, workersDone)
 	}
+	cancel()
+	slog.Info("Exiting", "code", code)
+	os.Exit(code)
 }
 
-func processItem(key string) {
-	defer queue.Done(key)
-	namespace, name, err := cache.SplitMetaNamespaceKey(key)
-	if err != nil {
-		log.Printf("\nError splitting key into parts %v", err)
+// runMetrics serves the controller's prometheus metrics on metricsAddress
+func runMetrics(c *controller) {
+	if metricsAddress == "" {
+		slog.Info("Metrics disabled")
 		return
 	}
+	metrics.RegisterEnvoyCount(c.countEnvoys)
+	metrics.RegisterXDSVersions(c.xdsServer.Versions)
+	metricsServer := metrics.NewServer()
+	serve("metrics", func() error { return metricsServer.Run(metricsAddress, stopCh) })
+}
 
-	//retrieve the object
-	obj, err := sharedFactory.Example().V1().Envoys().Lister().Envoys(namespace).Get(name)
-	if err != nil {
-		log.Printf("\nError getting object %s %s from api %s", namespace, name, err)
-	}
-
-	//Reconcile expected state with current state
-	if err := reconcile(obj, namespace, name); err != nil {
-		log.Printf("\nError reconciling object %v", err)
-		return
+// countEnvoys returns the number of envoys in the informer caches per namespace
+func (c *controller) countEnvoys() map[string]int {
+	counts := map[string]int{}
+	for _, f := range c.sharedFactories {
+		envoys, err := f.Envoy().V1().Envoys().Lister().List(labels.Everything())
+		if err != nil {
+			slog.Error("Error listing envoys", "err", err)
+			continue
+		}
+		for _, envoy := range envoys {
+			counts[envoy.Namespace]++
+		}
 	}
+	return counts
 }
 
-func reconcile(envoy *v1.Envoy, namespace string, name string) error {
-	deploymentName := envoy.Spec.Name
-	if deploymentName == "" {
-		log.Printf("%s: deployment name must be specified", name)
-		return nil
+// runWebhooks serves sidecar injection and envoy defaulting, validation and conversion when
+// webhookCertDir holds tls.crt and tls.key
+func runWebhooks(clientset client.Interface, kubeclientset kubernetes.Interface) {
+	certFile, keyFile := filepath.Join(webhookCertDir, "tls.crt"), filepath.Join(webhookCertDir, "tls.key")
+	if _, err := os.Stat(certFile); err != nil {
+		slog.Info("Webhooks disabled, no certificate", "err", err)
+		return
 	}
-	deploymentsClient := kubeclientset.AppsV1().Deployments(namespace)
-	svcClient := kubeclientset.CoreV1().Services(namespace)
-	cfgClient := kubeclientset.CoreV1().ConfigMaps(namespace)
 
-	_, err := cfgClient.Get(envoy.Spec.ConfigMapName, metav1.GetOptions{})
-	newConfigmapSpec := envoyutils.ConfigMap(envoy)
-	if errors.IsNotFound(err) {
-		log.Printf("Configmap not found %v", err)
-		cfgClient.Create(newConfigmapSpec)
+	// certificates are rotated in place, a pair that no longer loads fails readiness
+	probes.Readiness.Add("webhook", func() error {
+		_, err := tls.LoadX509KeyPair(certFile, keyFile)
+		return err
+	})
+	webhookServer := webhook.NewServer()
+	if webhook.InitImage != "" {
+		webhookServer.Handle("/inject", webhook.NewInjector(kubeclientset).Handler())
+	} else {
+		slog.Info("Sidecar injection disabled, no init image pinned by digest")
 	}
+	webhookServer.Handle("/default-envoy", webhook.NewDefaulter().Handler())
+	webhookServer.Handle("/validate-envoy", webhook.NewValidator(clientset, kubeclientset).Handler())
+	webhookServer.Handle("/convert", webhook.NewConverter(scheme.Scheme).Handler())
+	serve("webhook", func() error { return webhookServer.Run(webhookAddress, certFile, keyFile, stopCh) })
+}
 
-	deployment, err := deploymentsClient.Get(envoy.Spec.Name, metav1.GetOptions{})
+// runLeaderElection runs the controller as one of several replicas until ctx is cancelled or the lease
+// is lost, and returns the exit code. Until it holds the lease a replica only serves xds; once leading
+// it resyncs every envoy. A replica that lost its lease returns 1 at once, without waiting for its
+// workers, so that nothing it still had queued is written by two leaders. When ctx is cancelled the
+// workers drain while the lease is still held, and only then is it released for the next leader.
+func (c *controller) runLeaderElection(ctx context.Context, workersDone <-chan struct{}) int {
+	leaseCtx, release := context.WithCancel(context.Background())
+	defer release()
+	elected := make(chan error, 1)
+	go func() {
+		elected <- c.elector.Run(leaseCtx, func(ctx context.Context) {
+			c.enqueueAll()
+			<-ctx.Done()
+		})
+	}()
 
-	if errors.IsNotFound(err) {
-		log.Printf("Deployment not found %v", err)
-		newDeploymentSpec := envoyutils.Deployment(envoy)
-		deployment, _ = deploymentsClient.Create(newDeploymentSpec)
-		envoyutils.UpdateStatus(clientset, envoy, namespace, deployment)
-	}
-	if err == nil {
-		if envoy.Spec.Replicas != nil && *envoy.Spec.Replicas != *deployment.Spec.Replicas {
-			deployment, _ = deploymentsClient.Update(envoyutils.Deployment(envoy))
-			envoyutils.UpdateStatus(clientset, envoy, namespace, deployment)
-			log.Printf("Updating deployments")
+	select {
+	case err := <-elected:
+		if err != nil {
+			slog.Error("Leader election failed", "err", err)
+		} else {
+			slog.Warn("Lost leadership, exiting")
 		}
+		return 1
+	case <-ctx.Done():
 	}
-	newServiceSpec := envoyutils.Service(envoy)
-	_, err = svcClient.Get(envoy.Spec.Name, metav1.GetOptions{})
-	if errors.IsNotFound(err) {
-		svcClient.Create(newServiceSpec)
+	code := shutdown(c, workersDone)
+	release()
+	select {
+	case <-elected:
+		slog.Info("Released lease")
+	case <-time.After(shutdownTimeout):
+		slog.Error("Releasing the lease did not finish in time", "timeout", shutdownTimeout)
+		code = 1
 	}
-	return nil
+	return code
 }
 
-func enqueue(obj interface{}) {
-	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
-	if err != nil {
-		log.Printf("Error obtaining key %v", err)
-		return
-	}
-
-	queue.Add(key)
+// leaderConfig returns the lease from the controller config, identified by the hostname
+func leaderConfig(cfg config.LeaderElectionConfig) leader.Config {
+	config := leader.DefaultConfig()
+	config.Name, config.Namespace = cfg.Name, cfg.Namespace
+	config.LeaseDuration = cfg.LeaseDuration.Duration
+	config.RenewDeadline = cfg.RenewDeadline.Duration
+	config.RetryPeriod = cfg.RetryPeriod.Duration
+	return config
 }
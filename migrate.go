@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	client "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned"
+	"github.com/starizard/kube-envoy-controller/pkg/config"
+	envoyutils "github.com/starizard/kube-envoy-controller/pkg/envoy"
+)
+
+const (
+	// migratedToAnnotation marks a legacy object as migrated, it holds the uid of its copy
+	migratedToAnnotation = "envoy.starizard.io/migrated-to"
+	// migratedFromAnnotation holds the uid of the legacy object a copy was made from
+	migratedFromAnnotation = "envoy.starizard.io/migrated-from"
+	// legacyFinalizerName is finalizerName under the legacy group, dropped from migrated envoys
+	legacyFinalizerName = "envoys.example.com/cleanup"
+)
+
+// migrator copies the envoys, routes and listeners of a legacy API group into the current one. The
+// deployment, service and config map of a legacy envoy are released and adopted by its copy without
+// touching their pod template, so the proxies keep running. Legacy objects are left in place, marked
+// with migratedToAnnotation, and can be deleted once the copies are reconciled.
+type migrator struct {
+	dynamicclient dynamic.Interface
+	clientset     client.Interface
+	kubeclientset kubernetes.Interface
+	recorder      record.EventRecorder
+
+	legacy        schema.GroupVersion
+	namespaces    []string
+	labelSelector string
+}
+
+// newMigrator returns a migrator from the legacy group of cfg, limited to the namespaces and selector the controller watches
+func newMigrator(cfg config.Config, dynamicclient dynamic.Interface, clientset client.Interface, kubeclientset kubernetes.Interface, recorder record.EventRecorder) *migrator {
+	namespaces := cfg.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	return &migrator{
+		dynamicclient: dynamicclient,
+		clientset:     clientset,
+		kubeclientset: kubeclientset,
+		recorder:      recorder,
+		legacy:        schema.GroupVersion{Group: cfg.Migration.Group, Version: "v1"},
+		namespaces:    namespaces,
+		labelSelector: cfg.LabelSelector,
+	}
+}
+
+// run migrates every period until stopCh is closed, but only while leading reports true, so that
+// replicas do not copy the same objects twice
+func (m *migrator) run(period time.Duration, leading func() bool, stopCh <-chan struct{}) {
+	wait.Until(func() {
+		if !leading() {
+			return
+		}
+		if err := m.migrateAll(); err != nil {
+			slog.Error("Error migrating legacy objects", "group", m.legacy.Group, "err", err)
+		}
+	}, period, stopCh)
+}
+
+// migrateAll copies routes and listeners before envoys, so that the first reconcile of a copied
+// envoy already serves them
+func (m *migrator) migrateAll() error {
+	for _, namespace := range m.namespaces {
+		if err := m.migrateKind(namespace, "envoyroutes", m.migrateRoute); err != nil {
+			return err
+		}
+		if err := m.migrateKind(namespace, "envoylisteners", m.migrateListener); err != nil {
+			return err
+		}
+		if err := m.migrateKind(namespace, "envoys", m.migrateEnvoy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateKind copies the legacy objects of resource in namespace that are not migrated yet with
+// migrate, which returns the uid of the copy, and marks them as migrated
+func (m *migrator) migrateKind(namespace, resource string, migrate func(*unstructured.Unstructured) (types.UID, error)) error {
+	client := m.dynamicclient.Resource(m.legacy.WithResource(resource))
+	legacy, err := client.Namespace(namespace).List(metav1.ListOptions{LabelSelector: m.labelSelector})
+	if errors.IsNotFound(err) {
+		// the legacy CRD is gone, there is nothing left to migrate
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("listing %s.%s: %v", resource, m.legacy.Group, err)
+	}
+	for i := range legacy.Items {
+		obj := &legacy.Items[i]
+		if _, done := obj.GetAnnotations()[migratedToAnnotation]; done || obj.GetDeletionTimestamp() != nil {
+			continue
+		}
+		log := slog.With("resource", resource, "namespace", obj.GetNamespace(), "name", obj.GetName())
+		uid, err := migrate(obj)
+		if err != nil {
+			// one object that cannot be copied must not hold back the others
+			log.Error("Error migrating legacy object", "err", err)
+			continue
+		}
+
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[migratedToAnnotation] = string(uid)
+		obj.SetAnnotations(annotations)
+		var finalizers []string
+		for _, f := range obj.GetFinalizers() {
+			if f != legacyFinalizerName {
+				finalizers = append(finalizers, f)
+			}
+		}
+		obj.SetFinalizers(finalizers)
+		if _, err := client.Namespace(obj.GetNamespace()).Update(obj, metav1.UpdateOptions{}); err != nil {
+			log.Error("Error marking legacy object as migrated", "err", err)
+			continue
+		}
+		log.Info("Migrated legacy object", "uid", uid)
+	}
+	return nil
+}
+
+// migrateEnvoy releases the generated objects of a legacy envoy and copies it, the copy adopts them on its first reconcile
+func (m *migrator) migrateEnvoy(obj *unstructured.Unstructured) (types.UID, error) {
+	legacy := &v1.Envoy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), legacy); err != nil {
+		return "", err
+	}
+	existing, err := m.existingCopy(m.clientset.EnvoyV1().Envoys(legacy.Namespace).Get(legacy.Name, metav1.GetOptions{}))
+	if err != nil {
+		return "", err
+	}
+	var uid types.UID
+	if existing != nil {
+		if uid, err = m.copyOf(existing, legacy.UID); err != nil {
+			return "", err
+		}
+	}
+	// the names the legacy controller generated the objects under
+	named := legacy.DeepCopy()
+	envoyutils.Default(named)
+	if err := m.release(named); err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return uid, nil
+	}
+
+	envoy := &v1.Envoy{ObjectMeta: migratedMeta(legacy.ObjectMeta), Spec: legacy.Spec}
+	created, err := m.clientset.EnvoyV1().Envoys(envoy.Namespace).Create(envoy)
+	if err != nil {
+		return "", err
+	}
+	m.copyStatus(created, legacy)
+	m.recorder.Eventf(envoyReference(created), apiv1.EventTypeNormal, reasonMigrated, "Copied from %s envoy, adopting deployment %s, service %s and configmap %s",
+		m.legacy.Group, named.Spec.Name, named.Spec.Name, named.Spec.ConfigMapName)
+	return created.UID, nil
+}
+
+// copyStatus writes the status of a legacy envoy to its copy, which the api server left out on create,
+// so that the copy reports its fleet before the first reconcile. The copy starts over at generation
+// 1: a status that was up to date with the legacy spec is up to date with it. A status that cannot be
+// written is rebuilt by the controller.
+func (m *migrator) copyStatus(envoy, legacy *v1.Envoy) {
+	updated := envoy.DeepCopy()
+	updated.Status = *legacy.Status.DeepCopy()
+	updated.Status.ObservedGeneration = 0
+	if legacy.Status.ObservedGeneration == legacy.Generation {
+		updated.Status.ObservedGeneration = envoy.Generation
+	}
+	if _, err := m.clientset.EnvoyV1().Envoys(envoy.Namespace).UpdateStatus(updated); err != nil {
+		slog.Warn("Could not copy the status of a legacy envoy", "namespace", envoy.Namespace, "name", envoy.Name, "err", err)
+	}
+}
+
+// release drops the owner reference of the legacy envoy from the deployment, service and config map
+// it controls. Only metadata changes, the deployment does not roll its pods.
+func (m *migrator) release(legacy *v1.Envoy) error {
+	deploymentsClient := m.kubeclientset.AppsV1().Deployments(legacy.Namespace)
+	svcClient := m.kubeclientset.CoreV1().Services(legacy.Namespace)
+	cfgClient := m.kubeclientset.CoreV1().ConfigMaps(legacy.Namespace)
+
+	deployment, err := deploymentsClient.Get(legacy.Spec.Name, metav1.GetOptions{})
+	if err == nil && envoyutils.IsOwnedBy(deployment, legacy) {
+		deployment.OwnerReferences = withoutOwner(deployment.OwnerReferences, legacy)
+		_, err = deploymentsClient.Update(deployment)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	service, err := svcClient.Get(legacy.Spec.Name, metav1.GetOptions{})
+	if err == nil && envoyutils.IsOwnedBy(service, legacy) {
+		service.OwnerReferences = withoutOwner(service.OwnerReferences, legacy)
+		_, err = svcClient.Update(service)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	cfg, err := cfgClient.Get(legacy.Spec.ConfigMapName, metav1.GetOptions{})
+	if err == nil && envoyutils.IsOwnedBy(cfg, legacy) {
+		cfg.OwnerReferences = withoutOwner(cfg.OwnerReferences, legacy)
+		_, err = cfgClient.Update(cfg)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (m *migrator) migrateRoute(obj *unstructured.Unstructured) (types.UID, error) {
+	legacy := &v1.EnvoyRoute{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), legacy); err != nil {
+		return "", err
+	}
+	existing, err := m.existingCopy(m.clientset.EnvoyV1().EnvoyRoutes(legacy.Namespace).Get(legacy.Name, metav1.GetOptions{}))
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return m.copyOf(existing, legacy.UID)
+	}
+	// routes have no status subresource, the copy is created with the status. It names the envoys,
+	// whose copies keep their names.
+	route := &v1.EnvoyRoute{ObjectMeta: migratedMeta(legacy.ObjectMeta), Spec: legacy.Spec, Status: legacy.Status}
+	created, err := m.clientset.EnvoyV1().EnvoyRoutes(route.Namespace).Create(route)
+	if err != nil {
+		return "", err
+	}
+	return created.UID, nil
+}
+
+func (m *migrator) migrateListener(obj *unstructured.Unstructured) (types.UID, error) {
+	legacy := &v1.EnvoyListener{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), legacy); err != nil {
+		return "", err
+	}
+	existing, err := m.existingCopy(m.clientset.EnvoyV1().EnvoyListeners(legacy.Namespace).Get(legacy.Name, metav1.GetOptions{}))
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return m.copyOf(existing, legacy.UID)
+	}
+	listener := &v1.EnvoyListener{ObjectMeta: migratedMeta(legacy.ObjectMeta), Spec: legacy.Spec}
+	created, err := m.clientset.EnvoyV1().EnvoyListeners(listener.Namespace).Create(listener)
+	if err != nil {
+		return "", err
+	}
+	return created.UID, nil
+}
+
+// existingCopy turns the lookup of an object in the current group into the object, nil when there is none
+func (m *migrator) existingCopy(obj metav1.Object, err error) (metav1.Object, error) {
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// copyOf returns the uid of an object found in the current group under the name of a legacy one. A
+// previous pass that could not mark the legacy object made it; one created by hand is left alone.
+func (m *migrator) copyOf(obj metav1.Object, legacyUID types.UID) (types.UID, error) {
+	if obj.GetAnnotations()[migratedFromAnnotation] != string(legacyUID) {
+		return "", fmt.Errorf("%s/%s already exists in %s and was not migrated", obj.GetNamespace(), obj.GetName(), v1.SchemeGroupVersion.Group)
+	}
+	return obj.GetUID(), nil
+}
+
+// migratedMeta is the metadata of the copy of a legacy object: its name, labels and annotations
+func migratedMeta(legacy metav1.ObjectMeta) metav1.ObjectMeta {
+	annotations := map[string]string{}
+	for k, v := range legacy.Annotations {
+		annotations[k] = v
+	}
+	annotations[migratedFromAnnotation] = string(legacy.UID)
+	return metav1.ObjectMeta{
+		Name:        legacy.Name,
+		Namespace:   legacy.Namespace,
+		Labels:      legacy.Labels,
+		Annotations: annotations,
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	core "k8s.io/client-go/testing"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	"github.com/starizard/kube-envoy-controller/pkg/config"
+)
+
+var legacyGroup = schema.GroupVersion{Group: "example.com", Version: "v1"}
+
+// migration is a fixture whose migrator reads the legacy objects from a fake dynamic client
+type migration struct {
+	*fixture
+	dynamicclient *dynamicfake.FakeDynamicClient
+	m             *migrator
+}
+
+func newMigration(t *testing.T) *migration {
+	f := newFixture(t)
+	// the fake api server assigns no uids, the copies get one from their name
+	f.clientset.PrependReactor("create", "*", func(action core.Action) (bool, runtime.Object, error) {
+		if obj, err := meta.Accessor(action.(core.CreateAction).GetObject()); err == nil && obj.GetUID() == "" {
+			obj.SetUID(types.UID("copy-" + obj.GetName()))
+		}
+		return false, nil, nil
+	})
+	dynamicclient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	cfg := config.Default()
+	cfg.Migration.Enabled = true
+	return &migration{
+		fixture:       f,
+		dynamicclient: dynamicclient,
+		m:             newMigrator(cfg, dynamicclient, f.clientset, f.kubeclientset, f.recorder),
+	}
+}
+
+// legacyEnvoy returns an envoy as the legacy controller left it, reconciled and with its finalizer
+func legacyEnvoy() *v1.Envoy {
+	envoy := testEnvoy("edge")
+	envoy.UID = "legacy-edge"
+	envoy.Generation = 3
+	envoy.Finalizers = []string{legacyFinalizerName}
+	envoy.Status = v1.EnvoyStatus{
+		ObservedGeneration: 3,
+		AvailableReplicas:  1,
+		Replicas:           1,
+		DeploymentName:     "edge",
+		ServiceName:        "edge",
+		ConfigMapName:      "edge",
+		Conditions:         []v1.EnvoyCondition{{Type: v1.EnvoyReady, Status: apiv1.ConditionTrue, Reason: "Available"}},
+	}
+	return envoy
+}
+
+// addLegacy stores obj as a kind of the legacy group
+func (mf *migration) addLegacy(resource, kind string, obj runtime.Object) {
+	mf.t.Helper()
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		mf.t.Fatal(err)
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetAPIVersion(legacyGroup.String())
+	u.SetKind(kind)
+	if _, err := mf.dynamicclient.Resource(legacyGroup.WithResource(resource)).Namespace(u.GetNamespace()).Create(u, metav1.CreateOptions{}); err != nil {
+		mf.t.Fatal(err)
+	}
+}
+
+// legacy reads a legacy object back
+func (mf *migration) legacy(resource, name string) *unstructured.Unstructured {
+	mf.t.Helper()
+	u, err := mf.dynamicclient.Resource(legacyGroup.WithResource(resource)).Namespace("default").Get(name, metav1.GetOptions{})
+	if err != nil {
+		mf.t.Fatal(err)
+	}
+	return u
+}
+
+// addGenerated stores the deployment, service and config map envoy controls and returns the deployment
+func (mf *migration) addGenerated(envoy *v1.Envoy) *appsv1.Deployment {
+	cfgMap, deployment, service := mf.generated(envoy)
+	mf.addKube(cfgMap)
+	mf.addKube(deployment)
+	mf.addKube(service)
+	return deployment
+}
+
+// ownedBy reports whether the deployment, service and config map of envoy are controlled by uid
+func (mf *migration) ownedBy(envoy *v1.Envoy, uid types.UID) []string {
+	mf.t.Helper()
+	deployment, err := mf.kubeclientset.AppsV1().Deployments("default").Get(envoy.Spec.Name, metav1.GetOptions{})
+	if err != nil {
+		mf.t.Fatal(err)
+	}
+	service, err := mf.kubeclientset.CoreV1().Services("default").Get(envoy.Spec.Name, metav1.GetOptions{})
+	if err != nil {
+		mf.t.Fatal(err)
+	}
+	cfgMap, err := mf.kubeclientset.CoreV1().ConfigMaps("default").Get(envoy.Spec.ConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		mf.t.Fatal(err)
+	}
+	var owned []string
+	for kind, obj := range map[string]metav1.Object{"deployment": deployment, "service": service, "configmap": cfgMap} {
+		if ref := metav1.GetControllerOf(obj); ref != nil && ref.UID == uid {
+			owned = append(owned, kind)
+		}
+	}
+	return owned
+}
+
+func TestMigrateCopiesWithStatus(t *testing.T) {
+	mf := newMigration(t)
+	legacy := legacyEnvoy()
+	mf.addLegacy("envoys", "Envoy", legacy)
+	backend := v1.RouteBackend{Service: "api", Port: intstr.FromInt(80)}
+	mf.addLegacy("envoyroutes", "EnvoyRoute", &v1.EnvoyRoute{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "api", UID: "legacy-api"},
+		Spec: v1.EnvoyRouteSpec{VirtualHosts: []v1.VirtualHost{{
+			Name:    "api",
+			Domains: []string{"*"},
+			Routes:  []v1.Route{{Match: v1.RouteMatch{Prefix: "/"}, Backends: []v1.RouteBackend{backend}}},
+		}}},
+		Status: v1.EnvoyRouteStatus{Envoys: []v1.RouteEnvoyStatus{{Name: "edge", Accepted: true}}},
+	})
+	mf.addLegacy("envoylisteners", "EnvoyListener", &v1.EnvoyListener{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "legacy-web"},
+		Spec:       v1.EnvoyListenerSpec{Port: 8080, Protocol: v1.ListenerHTTP},
+	})
+
+	if err := mf.m.migrateAll(); err != nil {
+		t.Fatal(err)
+	}
+	copied := mf.stored(legacy)
+	if copied.Annotations[migratedFromAnnotation] != "legacy-edge" {
+		t.Errorf("copy annotated with %q, want the legacy uid", copied.Annotations[migratedFromAnnotation])
+	}
+	if !equality.Semantic.DeepEqual(copied.Spec, legacy.Spec) {
+		t.Errorf("copied spec %+v, want %+v", copied.Spec, legacy.Spec)
+	}
+	if n := len(mf.envoyActions("update", "envoys", "status")); n != 1 {
+		t.Fatalf("%d status updates, want 1", n)
+	}
+	// the legacy status was up to date, so is the copy's at its own generation
+	want := legacy.Status
+	want.ObservedGeneration = copied.Generation
+	if !equality.Semantic.DeepEqual(copied.Status, want) {
+		t.Errorf("copied status %+v, want %+v", copied.Status, want)
+	}
+	if len(copied.Finalizers) != 0 {
+		t.Errorf("copy carries finalizers %v", copied.Finalizers)
+	}
+
+	route, err := mf.clientset.EnvoyV1().EnvoyRoutes("default").Get("api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(route.Status.Envoys) != 1 || !route.Status.Envoys[0].Accepted {
+		t.Errorf("route copied with status %+v, want the legacy one", route.Status)
+	}
+	if _, err := mf.clientset.EnvoyV1().EnvoyListeners("default").Get("web", metav1.GetOptions{}); err != nil {
+		t.Errorf("listener not copied: %v", err)
+	}
+
+	for resource, name := range map[string]string{"envoys": "edge", "envoyroutes": "api", "envoylisteners": "web"} {
+		marked := mf.legacy(resource, name)
+		if marked.GetAnnotations()[migratedToAnnotation] != "copy-"+name {
+			t.Errorf("legacy %s %s marked with %q, want its copy's uid", resource, name, marked.GetAnnotations()[migratedToAnnotation])
+		}
+	}
+	if finalizers := mf.legacy("envoys", "edge").GetFinalizers(); len(finalizers) != 0 {
+		t.Errorf("legacy envoy keeps finalizers %v", finalizers)
+	}
+	mf.expectEvent("Normal Migrated Copied from example.com envoy")
+}
+
+func TestMigrateReleasesGeneratedObjects(t *testing.T) {
+	mf := newMigration(t)
+	legacy := legacyEnvoy()
+	mf.addLegacy("envoys", "Envoy", legacy)
+	before := mf.addGenerated(legacy)
+
+	if err := mf.m.migrateAll(); err != nil {
+		t.Fatal(err)
+	}
+	if owned := mf.ownedBy(legacy, legacy.UID); len(owned) != 0 {
+		t.Fatalf("%v still controlled by the legacy envoy", owned)
+	}
+	for _, resource := range []string{"deployments", "services", "configmaps"} {
+		if n := len(mf.kubeActions("update", resource)); n != 1 {
+			t.Errorf("%d %s updates, want 1", n, resource)
+		}
+	}
+	after, err := mf.kubeclientset.AppsV1().Deployments("default").Get("edge", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equality.Semantic.DeepEqual(after.Spec, before.Spec) {
+		t.Fatal("releasing the deployment changed its spec, the pods would roll")
+	}
+
+	// the copy adopts them on its first reconcile
+	copied := mf.stored(legacy)
+	if err := mf.c.sharedFactoryFor(copied.Namespace).Envoy().V1().Envoys().Informer().GetIndexer().Add(copied); err != nil {
+		t.Fatal(err)
+	}
+	factory := mf.c.kubeFactoryFor(metav1.NamespaceAll)
+	cfgMap, err := mf.kubeclientset.CoreV1().ConfigMaps("default").Get("edge", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, err := mf.kubeclientset.CoreV1().Services("default").Get("edge", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{
+		factory.Apps().V1().Deployments().Informer().GetIndexer().Add(after),
+		factory.Core().V1().Services().Informer().GetIndexer().Add(service),
+		factory.Core().V1().ConfigMaps().Informer().GetIndexer().Add(cfgMap),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mf.sync(copied); err != nil {
+		t.Fatal(err)
+	}
+	if owned := mf.ownedBy(copied, copied.UID); len(owned) != 3 {
+		t.Fatalf("copy controls only %v, want the deployment, service and config map", owned)
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	mf := newMigration(t)
+	legacy := legacyEnvoy()
+	mf.addLegacy("envoys", "Envoy", legacy)
+	mf.addGenerated(legacy)
+	if err := mf.m.migrateAll(); err != nil {
+		t.Fatal(err)
+	}
+	mf.clientset.ClearActions()
+	mf.kubeclientset.ClearActions()
+	mf.dynamicclient.ClearActions()
+
+	if err := mf.m.migrateAll(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(mf.clientset.Actions()); n != 0 {
+		t.Errorf("%d requests to the envoy api on a second pass, want none", n)
+	}
+	if n := len(mf.kubeclientset.Actions()); n != 0 {
+		t.Errorf("%d requests to the kube api on a second pass, want none", n)
+	}
+	for _, a := range mf.dynamicclient.Actions() {
+		if a.GetVerb() != "list" {
+			t.Errorf("second pass %s %s", a.GetVerb(), a.GetResource().Resource)
+		}
+	}
+}
+
+func TestMigrateResumesAfterUnmarkedCopy(t *testing.T) {
+	mf := newMigration(t)
+	legacy := legacyEnvoy()
+	mf.addLegacy("envoys", "Envoy", legacy)
+	mf.addGenerated(legacy)
+	// a previous pass copied the envoy but could not mark the legacy one
+	copied := testEnvoy("edge")
+	copied.UID = "copy-edge"
+	copied.Annotations = map[string]string{migratedFromAnnotation: "legacy-edge"}
+	if err := mf.clientset.Tracker().Create(envoysResource, copied, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mf.m.migrateAll(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(mf.envoyActions("create", "envoys", "")); n != 0 {
+		t.Fatalf("%d envoys created, want the existing copy reused", n)
+	}
+	if got := mf.legacy("envoys", "edge").GetAnnotations()[migratedToAnnotation]; got != "copy-edge" {
+		t.Fatalf("legacy envoy marked with %q, want the existing copy's uid", got)
+	}
+	if owned := mf.ownedBy(legacy, legacy.UID); len(owned) != 0 {
+		t.Fatalf("%v still controlled by the legacy envoy", owned)
+	}
+}
+
+func TestMigrateLeavesUnrelatedTarget(t *testing.T) {
+	mf := newMigration(t)
+	legacy := legacyEnvoy()
+	mf.addLegacy("envoys", "Envoy", legacy)
+	mf.addGenerated(legacy)
+	// created by hand under the same name, not by a migration
+	existing := testEnvoy("edge")
+	if err := mf.clientset.Tracker().Create(envoysResource, existing, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mf.m.migrateAll(); err != nil {
+		t.Fatal(err)
+	}
+	if _, marked := mf.legacy("envoys", "edge").GetAnnotations()[migratedToAnnotation]; marked {
+		t.Fatal("legacy envoy marked as migrated to an envoy it was not copied to")
+	}
+	if n := len(mf.kubeActions("update", "deployments")) + len(mf.kubeActions("update", "services")) + len(mf.kubeActions("update", "configmaps")); n != 0 {
+		t.Fatalf("%d generated objects released to an envoy that was not copied", n)
+	}
+	if stored := mf.stored(existing); !equality.Semantic.DeepEqual(stored, existing) {
+		t.Fatalf("existing envoy changed to %+v", stored)
+	}
+}
+
+func TestMigrateSkipsLegacyBeingDeleted(t *testing.T) {
+	mf := newMigration(t)
+	legacy := legacyEnvoy()
+	now := metav1.Now()
+	legacy.DeletionTimestamp = &now
+	mf.addLegacy("envoys", "Envoy", legacy)
+	mf.addGenerated(legacy)
+
+	if err := mf.m.migrateAll(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(mf.envoyActions("create", "envoys", "")); n != 0 {
+		t.Fatalf("%d envoys copied from one being deleted", n)
+	}
+	if owned := mf.ownedBy(legacy, legacy.UID); len(owned) != 3 {
+		t.Fatalf("only %v left to the legacy envoy's garbage collection", owned)
+	}
+	if _, marked := mf.legacy("envoys", "edge").GetAnnotations()[migratedToAnnotation]; marked {
+		t.Fatal("legacy envoy being deleted marked as migrated")
+	}
+}
@@ -0,0 +1,20 @@
+// Package conversion converts the versions of a kind through one hub version: every other version
+// only knows how to convert to and from the hub, never to each other.
+package conversion
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Hub is the version of a kind every other version converts through, usually the storage version
+type Hub interface {
+	runtime.Object
+	Hub()
+}
+
+// Convertible is a version of a kind that converts to and from its hub
+type Convertible interface {
+	runtime.Object
+	ConvertTo(dst Hub) error
+	ConvertFrom(src Hub) error
+}
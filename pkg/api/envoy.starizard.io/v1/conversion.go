@@ -0,0 +1,4 @@
+package v1
+
+// Hub marks v1 as the version envoys are stored in, the other versions convert to and from it
+func (*Envoy) Hub() {}
@@ -0,0 +1,6 @@
+// +k8s:deepcopy-gen=package,register
+// +groupName=envoy.starizard.io
+package v1
+
+// The CustomResourceDefinitions in crds/ are generated from the markers on the types of every version
+//go:generate controller-gen crd:crdVersions=v1 paths=../... output:crd:artifacts:config=../../../../crds
@@ -0,0 +1,401 @@
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// +genclient
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +resource:path=envoys
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=envoys,scope=Namespaced
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Replicas",type=integer,JSONPath=".spec.replicas"
+// +kubebuilder:printcolumn:name="Available",type=integer,JSONPath=".status.availableReplicas"
+// +kubebuilder:printcolumn:name="XDS",type=string,JSONPath=".status.conditions[?(@.type==\"XDSConnected\")].reason"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+
+type Envoy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   EnvoySpec   `json:"spec"`
+	Status EnvoyStatus `json:"status,omitempty"`
+}
+
+type EnvoySpec struct {
+	// Name names the generated deployment and service, defaults to the envoy's name
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name,omitempty"`
+	// ConfigMapName names the generated bootstrap config map, defaults to the envoy's name
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	ConfigMapName string `json:"configMapName,omitempty"`
+	// Replicas of the generated deployment, defaults to 1
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+	// XDS defaults to the controller's builtIn xds server
+	// +optional
+	XDS EnvoyXDS `json:"xds"`
+	// Image is the envoy image of the fleet, defaults to the image configured for the controller
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image,omitempty"`
+	// AdminPort is where the envoy admin interface listens on localhost, defaults to the port
+	// configured for the controller
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	AdminPort int32 `json:"adminPort,omitempty"`
+	// ServiceSelector picks the services in this namespace that builtIn xds exposes as clusters,
+	// nil exposes none
+	// +optional
+	ServiceSelector *metav1.LabelSelector `json:"serviceSelector,omitempty"`
+	// DeletionPolicy decides what happens to the generated deployment, service and config map
+	// when the envoy is deleted, defaults to Delete
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// Rollout bounds how many envoy pods are replaced at once when the bootstrap or spec changes
+	// +optional
+	Rollout *EnvoyRollout `json:"rollout,omitempty"`
+	// Resources of the envoy container
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// NodeSelector and Tolerations place the envoy pods
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Listeners are opened on this envoy only. They claim their ports before the envoy listeners
+	// selecting it.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Listeners []InlineListener `json:"listeners,omitempty"`
+	// BootstrapOverrides is a JSON merge patch applied to the generated bootstrap, e.g. to add
+	// static clusters or tune the admin interface
+	// +optional
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	BootstrapOverrides *runtime.RawExtension `json:"bootstrapOverrides,omitempty"`
+}
+
+// InlineListener is an envoy listener declared on the envoy it opens on
+// +kubebuilder:validation:XValidation:rule="self.protocol != 'TCP' || has(self.backend)",message="TCP listeners need a backend"
+// +kubebuilder:validation:XValidation:rule="self.protocol != 'TLS' || has(self.tlsRoutes)",message="TLS listeners need at least one tlsRoute"
+type InlineListener struct {
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+	// Port is the container port envoy listens on
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+	// ServicePort is the port exposed on the envoy service, defaults to Port
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	ServicePort int32            `json:"servicePort,omitempty"`
+	Protocol    ListenerProtocol `json:"protocol"`
+	// +optional
+	Backend *RouteBackend `json:"backend,omitempty"`
+	// +optional
+	// +kubebuilder:validation:MinItems=1
+	TLSRoutes []TLSRoute `json:"tlsRoutes,omitempty"`
+}
+
+// EnvoyRollout is the rolling update strategy of the envoy deployment, both default to 25%
+type EnvoyRollout struct {
+	MaxSurge       *intstr.IntOrString `json:"maxSurge,omitempty"`
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Delete;Orphan
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete lets the generated objects be garbage collected with the envoy
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyOrphan leaves the generated objects running, e.g. to hand a fleet over to a new envoy
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// EnvoyXDS is the management server a fleet fetches its configuration from
+// +kubebuilder:validation:XValidation:rule="(has(self.builtIn) && self.builtIn) || has(self.host) == has(self.port)",message="host and port are set together unless builtIn is set"
+type EnvoyXDS struct {
+	// Name is the name of the xds cluster in the bootstrap, defaults to xds_cluster
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name,omitempty"`
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	Host string `json:"host,omitempty"`
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int `json:"port,omitempty"`
+	// BuiltIn points the fleet at the controller's own xds server, Host and Port are ignored.
+	// It defaults to true when neither Host nor Port is set.
+	// +optional
+	BuiltIn bool `json:"builtIn,omitempty"`
+}
+
+type EnvoyStatus struct {
+	// ObservedGeneration is the generation of the spec this status was computed for
+	ObservedGeneration int64            `json:"observedGeneration,omitempty"`
+	Conditions         []EnvoyCondition `json:"conditions,omitempty"`
+
+	AvailableReplicas int32 `json:"availableReplicas"`
+	// Replicas and UpdatedReplicas count all envoy pods and those running the current bootstrap
+	Replicas        int32        `json:"replicas,omitempty"`
+	UpdatedReplicas int32        `json:"updatedReplicas,omitempty"`
+	Rollout         RolloutState `json:"rollout,omitempty"`
+
+	// DeploymentName, ServiceName and ConfigMapName are the objects generated for this envoy
+	DeploymentName string `json:"deploymentName,omitempty"`
+	ServiceName    string `json:"serviceName,omitempty"`
+	ConfigMapName  string `json:"configMapName,omitempty"`
+	// ServiceAddress is the cluster ip of the envoy service
+	ServiceAddress string `json:"serviceAddress,omitempty"`
+	// BootstrapHash is the hash of the bootstrap in the config map, see the pod template annotation
+	BootstrapHash string `json:"bootstrapHash,omitempty"`
+}
+
+type EnvoyConditionType string
+
+const (
+	// EnvoyReady is true when every replica runs the current bootstrap and the service is in place
+	EnvoyReady EnvoyConditionType = "Ready"
+	// EnvoyProgressing is true while pods are being rolled
+	EnvoyProgressing EnvoyConditionType = "Progressing"
+	// EnvoyConfigRendered is true when the bootstrap config map matches the spec
+	EnvoyConfigRendered EnvoyConditionType = "ConfigRendered"
+	// EnvoyXDSConnected is true when proxies of a builtIn fleet are connected to the controller
+	EnvoyXDSConnected EnvoyConditionType = "XDSConnected"
+	// EnvoyDegraded is true when the controller failed to sync the fleet or its rollout is stuck
+	EnvoyDegraded EnvoyConditionType = "Degraded"
+)
+
+type EnvoyCondition struct {
+	Type               EnvoyConditionType     `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
+}
+
+type RolloutState string
+
+const (
+	// RolloutProgressing means pods with an older bootstrap or spec are still being replaced
+	RolloutProgressing RolloutState = "Progressing"
+	// RolloutComplete means every pod is updated and available
+	RolloutComplete RolloutState = "Complete"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type EnvoyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []Envoy `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +resource:path=envoyroutes
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=envoyroutes,scope=Namespaced
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+
+// EnvoyRoute is a set of virtual hosts served over RDS to the builtIn xds envoys it selects
+type EnvoyRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   EnvoyRouteSpec   `json:"spec"`
+	Status EnvoyRouteStatus `json:"status,omitempty"`
+}
+
+type EnvoyRouteSpec struct {
+	// EnvoySelector picks the envoys in this namespace that serve these routes
+	EnvoySelector metav1.LabelSelector `json:"envoySelector"`
+	// VirtualHosts and their routes are bounded so that the api server can afford the CEL rules on every route
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=64
+	// +listType=map
+	// +listMapKey=name
+	VirtualHosts []VirtualHost `json:"virtualHosts"`
+}
+
+type VirtualHost struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// +kubebuilder:validation:MinItems=1
+	Domains []string `json:"domains"`
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=128
+	Routes []Route `json:"routes"`
+}
+
+// Route sends matching requests either to weighted backends or to a redirect
+// +kubebuilder:validation:XValidation:rule="has(self.backends) != has(self.redirect)",message="exactly one of backends or redirect must be set"
+type Route struct {
+	Match RouteMatch `json:"match"`
+	// +optional
+	// +kubebuilder:validation:MinItems=1
+	Backends []RouteBackend `json:"backends,omitempty"`
+	// +optional
+	Redirect *RouteRedirect `json:"redirect,omitempty"`
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// +optional
+	Retries *RouteRetries `json:"retries,omitempty"`
+}
+
+// RouteMatch matches on Path exactly, or on Prefix ("/" when both are empty), plus every header and query param
+// +kubebuilder:validation:XValidation:rule="!(has(self.path) && has(self.prefix))",message="path and prefix are mutually exclusive"
+type RouteMatch struct {
+	Prefix      string            `json:"prefix,omitempty"`
+	Path        string            `json:"path,omitempty"`
+	Headers     []HeaderMatch     `json:"headers,omitempty"`
+	QueryParams []QueryParamMatch `json:"queryParams,omitempty"`
+}
+
+// HeaderMatch matches Value exactly, an empty Value only requires the header to be present
+type HeaderMatch struct {
+	// +kubebuilder:validation:MinLength=1
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+// QueryParamMatch matches Value exactly, an empty Value only requires the param to be present
+type QueryParamMatch struct {
+	// +kubebuilder:validation:MinLength=1
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+// RouteBackend is a port of a service exposed to the envoy through its serviceSelector
+type RouteBackend struct {
+	// +kubebuilder:validation:MinLength=1
+	Service string `json:"service"`
+	// Port is the number or name of a service port
+	Port intstr.IntOrString `json:"port"`
+	// Weight is the share of requests sent to this backend, backends all left without one split evenly
+	// +optional
+	Weight uint32 `json:"weight,omitempty"`
+}
+
+type RouteRedirect struct {
+	Host  string `json:"host,omitempty"`
+	Path  string `json:"path,omitempty"`
+	HTTPS bool   `json:"https,omitempty"`
+	// Code is 301, 302, 303, 307 or 308, defaults to 301
+	// +optional
+	// +kubebuilder:validation:Enum=301;302;303;307;308
+	Code int `json:"code,omitempty"`
+}
+
+type RouteRetries struct {
+	Attempts uint32 `json:"attempts"`
+	// On is envoy's retry_on, defaults to "5xx"
+	On            string           `json:"on,omitempty"`
+	PerTryTimeout *metav1.Duration `json:"perTryTimeout,omitempty"`
+}
+
+type EnvoyRouteStatus struct {
+	Envoys []RouteEnvoyStatus `json:"envoys,omitempty"`
+}
+
+// RouteEnvoyStatus records whether a selected envoy serves the route
+type RouteEnvoyStatus struct {
+	Name     string `json:"name"`
+	Accepted bool   `json:"accepted"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type EnvoyRouteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []EnvoyRoute `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +resource:path=envoylisteners
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=envoylisteners,scope=Namespaced
+// +kubebuilder:printcolumn:name="Port",type=integer,JSONPath=".spec.port"
+// +kubebuilder:printcolumn:name="Protocol",type=string,JSONPath=".spec.protocol"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+
+// EnvoyListener opens a port on the envoys it selects and is served over LDS to builtIn xds envoys
+type EnvoyListener struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec EnvoyListenerSpec `json:"spec"`
+}
+
+// +kubebuilder:validation:Enum=HTTP;TCP;TLS
+type ListenerProtocol string
+
+const (
+	// ListenerHTTP serves the fleet's RDS routes
+	ListenerHTTP ListenerProtocol = "HTTP"
+	// ListenerTCP proxies connections to Backend
+	ListenerTCP ListenerProtocol = "TCP"
+	// ListenerTLS passes TLS connections through to a backend picked by SNI
+	ListenerTLS ListenerProtocol = "TLS"
+)
+
+// +kubebuilder:validation:XValidation:rule="self.protocol != 'TCP' || has(self.backend)",message="TCP listeners need a backend"
+// +kubebuilder:validation:XValidation:rule="self.protocol != 'TLS' || has(self.tlsRoutes)",message="TLS listeners need at least one tlsRoute"
+type EnvoyListenerSpec struct {
+	// EnvoySelector picks the envoys in this namespace that open this listener
+	EnvoySelector metav1.LabelSelector `json:"envoySelector"`
+	// Port is the container port envoy listens on
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+	// ServicePort is the port exposed on the envoy service, defaults to Port
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	ServicePort int32            `json:"servicePort,omitempty"`
+	Protocol    ListenerProtocol `json:"protocol"`
+	// +optional
+	Backend *RouteBackend `json:"backend,omitempty"`
+	// +optional
+	// +kubebuilder:validation:MinItems=1
+	TLSRoutes []TLSRoute `json:"tlsRoutes,omitempty"`
+}
+
+// TLSRoute sends TLS connections for ServerNames to Backend, an empty ServerNames matches any SNI
+type TLSRoute struct {
+	ServerNames []string     `json:"serverNames,omitempty"`
+	Backend     RouteBackend `json:"backend"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type EnvoyListenerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []EnvoyListener `json:"items"`
+}
@@ -0,0 +1,158 @@
+package v1alpha2
+
+import (
+	"fmt"
+
+	"github.com/starizard/kube-envoy-controller/pkg/api/conversion"
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+)
+
+// ConvertTo converts this envoy to the v1 hub
+func (src *Envoy) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1.Envoy)
+	if !ok {
+		return fmt.Errorf("cannot convert envoy %s to %T", src.Name, dstRaw)
+	}
+	in := src.DeepCopy()
+	dst.ObjectMeta = in.ObjectMeta
+
+	dst.Spec = v1.EnvoySpec{
+		Name:          in.Spec.Deployment.Name,
+		ConfigMapName: in.Spec.Bootstrap.ConfigMapName,
+		Replicas:      in.Spec.Deployment.Replicas,
+		XDS: v1.EnvoyXDS{
+			Name:    in.Spec.Bootstrap.XDS.ClusterName,
+			Host:    in.Spec.Bootstrap.XDS.Host,
+			Port:    in.Spec.Bootstrap.XDS.Port,
+			BuiltIn: in.Spec.Bootstrap.XDS.BuiltIn,
+		},
+		Image:              in.Spec.Deployment.Template.Image,
+		AdminPort:          in.Spec.Bootstrap.AdminPort,
+		ServiceSelector:    in.Spec.ServiceSelector,
+		DeletionPolicy:     v1.DeletionPolicy(in.Spec.DeletionPolicy),
+		Resources:          in.Spec.Deployment.Template.Resources,
+		NodeSelector:       in.Spec.Deployment.Template.NodeSelector,
+		Tolerations:        in.Spec.Deployment.Template.Tolerations,
+		BootstrapOverrides: in.Spec.Bootstrap.Overrides,
+	}
+	for _, l := range in.Spec.Listeners {
+		listener := v1.InlineListener{
+			Name:        l.Name,
+			Port:        l.Port,
+			ServicePort: l.ServicePort,
+			Protocol:    v1.ListenerProtocol(l.Protocol),
+		}
+		if l.Backend != nil {
+			backend := v1.RouteBackend(*l.Backend)
+			listener.Backend = &backend
+		}
+		for _, r := range l.TLSRoutes {
+			listener.TLSRoutes = append(listener.TLSRoutes, v1.TLSRoute{ServerNames: r.ServerNames, Backend: v1.RouteBackend(r.Backend)})
+		}
+		dst.Spec.Listeners = append(dst.Spec.Listeners, listener)
+	}
+	if rollout := in.Spec.Deployment.Rollout; rollout != nil {
+		dst.Spec.Rollout = &v1.EnvoyRollout{MaxSurge: rollout.MaxSurge, MaxUnavailable: rollout.MaxUnavailable}
+	}
+
+	dst.Status = v1.EnvoyStatus{
+		ObservedGeneration: in.Status.ObservedGeneration,
+		AvailableReplicas:  in.Status.AvailableReplicas,
+		Replicas:           in.Status.Replicas,
+		UpdatedReplicas:    in.Status.UpdatedReplicas,
+		Rollout:            v1.RolloutState(in.Status.Rollout),
+		DeploymentName:     in.Status.DeploymentName,
+		ServiceName:        in.Status.ServiceName,
+		ConfigMapName:      in.Status.ConfigMapName,
+		ServiceAddress:     in.Status.ServiceAddress,
+		BootstrapHash:      in.Status.BootstrapHash,
+	}
+	for _, c := range in.Status.Conditions {
+		dst.Status.Conditions = append(dst.Status.Conditions, v1.EnvoyCondition{
+			Type:               v1.EnvoyConditionType(c.Type),
+			Status:             c.Status,
+			LastTransitionTime: c.LastTransitionTime,
+			Reason:             c.Reason,
+			Message:            c.Message,
+		})
+	}
+	return nil
+}
+
+// ConvertFrom converts a v1 hub envoy to this version
+func (dst *Envoy) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1.Envoy)
+	if !ok {
+		return fmt.Errorf("cannot convert %T to envoy %s", srcRaw, dst.Name)
+	}
+	in := src.DeepCopy()
+	dst.ObjectMeta = in.ObjectMeta
+
+	dst.Spec = EnvoySpec{
+		Deployment: EnvoyDeployment{
+			Name:     in.Spec.Name,
+			Replicas: in.Spec.Replicas,
+			Template: EnvoyPodTemplate{
+				Image:        in.Spec.Image,
+				Resources:    in.Spec.Resources,
+				NodeSelector: in.Spec.NodeSelector,
+				Tolerations:  in.Spec.Tolerations,
+			},
+		},
+		Bootstrap: EnvoyBootstrap{
+			ConfigMapName: in.Spec.ConfigMapName,
+			AdminPort:     in.Spec.AdminPort,
+			XDS: EnvoyXDS{
+				ClusterName: in.Spec.XDS.Name,
+				BuiltIn:     in.Spec.XDS.BuiltIn,
+				Host:        in.Spec.XDS.Host,
+				Port:        in.Spec.XDS.Port,
+			},
+			Overrides: in.Spec.BootstrapOverrides,
+		},
+		ServiceSelector: in.Spec.ServiceSelector,
+		DeletionPolicy:  DeletionPolicy(in.Spec.DeletionPolicy),
+	}
+	for _, l := range in.Spec.Listeners {
+		listener := Listener{
+			Name:        l.Name,
+			Port:        l.Port,
+			ServicePort: l.ServicePort,
+			Protocol:    ListenerProtocol(l.Protocol),
+		}
+		if l.Backend != nil {
+			backend := Backend(*l.Backend)
+			listener.Backend = &backend
+		}
+		for _, r := range l.TLSRoutes {
+			listener.TLSRoutes = append(listener.TLSRoutes, TLSRoute{ServerNames: r.ServerNames, Backend: Backend(r.Backend)})
+		}
+		dst.Spec.Listeners = append(dst.Spec.Listeners, listener)
+	}
+	if rollout := in.Spec.Rollout; rollout != nil {
+		dst.Spec.Deployment.Rollout = &EnvoyRollout{MaxSurge: rollout.MaxSurge, MaxUnavailable: rollout.MaxUnavailable}
+	}
+
+	dst.Status = EnvoyStatus{
+		ObservedGeneration: in.Status.ObservedGeneration,
+		AvailableReplicas:  in.Status.AvailableReplicas,
+		Replicas:           in.Status.Replicas,
+		UpdatedReplicas:    in.Status.UpdatedReplicas,
+		Rollout:            RolloutState(in.Status.Rollout),
+		DeploymentName:     in.Status.DeploymentName,
+		ServiceName:        in.Status.ServiceName,
+		ConfigMapName:      in.Status.ConfigMapName,
+		ServiceAddress:     in.Status.ServiceAddress,
+		BootstrapHash:      in.Status.BootstrapHash,
+	}
+	for _, c := range in.Status.Conditions {
+		dst.Status.Conditions = append(dst.Status.Conditions, EnvoyCondition{
+			Type:               EnvoyConditionType(c.Type),
+			Status:             c.Status,
+			LastTransitionTime: c.LastTransitionTime,
+			Reason:             c.Reason,
+			Message:            c.Message,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package v1alpha2
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	fuzz "github.com/google/gofuzz"
+	"k8s.io/apimachinery/pkg/api/apitesting/fuzzer"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metafuzzer "k8s.io/apimachinery/pkg/apis/meta/fuzzer"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/diff"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+)
+
+// overridesFuzzerFuncs fills bootstrap overrides with a JSON object, which is all the schema admits
+func overridesFuzzerFuncs(codecs serializer.CodecFactory) []interface{} {
+	return []interface{}{
+		func(r *runtime.RawExtension, c fuzz.Continue) {
+			r.Raw = []byte(fmt.Sprintf(`{"admin":{"profile_path":%q}}`, c.RandString()))
+		},
+	}
+}
+
+func roundTripFuzzer(t *testing.T) *fuzz.Fuzzer {
+	scheme := runtime.NewScheme()
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	seed := time.Now().UnixNano()
+	t.Logf("fuzzing with seed %d", seed)
+	funcs := fuzzer.MergeFuzzerFuncs(metafuzzer.Funcs, overridesFuzzerFuncs)
+	return fuzzer.FuzzerFor(funcs, rand.NewSource(seed), serializer.NewCodecFactory(scheme)).NilChance(0.2)
+}
+
+func TestRoundTripThroughHub(t *testing.T) {
+	f := roundTripFuzzer(t)
+	for i := 0; i < 1000; i++ {
+		in := &Envoy{}
+		f.Fuzz(in)
+		hub := &v1.Envoy{}
+		if err := in.ConvertTo(hub); err != nil {
+			t.Fatal(err)
+		}
+		out := &Envoy{}
+		if err := out.ConvertFrom(hub); err != nil {
+			t.Fatal(err)
+		}
+		if !apiequality.Semantic.DeepEqual(in, out) {
+			t.Fatalf("v1alpha2 changed through v1:\n%s", diff.ObjectReflectDiff(in, out))
+		}
+	}
+}
+
+func TestRoundTripFromHub(t *testing.T) {
+	f := roundTripFuzzer(t)
+	for i := 0; i < 1000; i++ {
+		in := &v1.Envoy{}
+		f.Fuzz(in)
+		spoke := &Envoy{}
+		if err := spoke.ConvertFrom(in); err != nil {
+			t.Fatal(err)
+		}
+		out := &v1.Envoy{}
+		if err := spoke.ConvertTo(out); err != nil {
+			t.Fatal(err)
+		}
+		if !apiequality.Semantic.DeepEqual(in, out) {
+			t.Fatalf("v1 changed through v1alpha2:\n%s", diff.ObjectReflectDiff(in, out))
+		}
+	}
+}
@@ -0,0 +1,3 @@
+// +k8s:deepcopy-gen=package,register
+// +groupName=envoy.starizard.io
+package v1alpha2
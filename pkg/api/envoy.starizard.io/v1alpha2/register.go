@@ -1,4 +1,4 @@
-package v1
+package v1alpha2
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -7,8 +7,8 @@ import (
 )
 
 var SchemeGroupVersion = schema.GroupVersion{
-	Group:   "example.com",
-	Version: "v1",
+	Group:   "envoy.starizard.io",
+	Version: "v1alpha2",
 }
 
 var (
@@ -0,0 +1,235 @@
+package v1alpha2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// +genclient
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +resource:path=envoys
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=envoys,scope=Namespaced
+// +kubebuilder:subresource:status
+// +kubebuilder:unservedversion
+// +kubebuilder:printcolumn:name="Replicas",type=integer,JSONPath=".spec.deployment.replicas"
+// +kubebuilder:printcolumn:name="Available",type=integer,JSONPath=".status.availableReplicas"
+// +kubebuilder:printcolumn:name="XDS",type=string,JSONPath=".status.conditions[?(@.type==\"XDSConnected\")].reason"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+
+// Envoy is the v1alpha2 shape of an envoy fleet: what v1 keeps flat is grouped by the object it ends
+// up in, the deployment and its pod template or the bootstrap, so that each can grow on its own.
+// Envoys are stored as v1 and converted by the controller's conversion webhook.
+type Envoy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   EnvoySpec   `json:"spec"`
+	Status EnvoyStatus `json:"status,omitempty"`
+}
+
+type EnvoySpec struct {
+	// Deployment is the generated deployment running the proxies
+	// +optional
+	Deployment EnvoyDeployment `json:"deployment"`
+	// Bootstrap is the generated config map the proxies start from
+	// +optional
+	Bootstrap EnvoyBootstrap `json:"bootstrap"`
+	// Listeners are opened on this envoy only. They claim their ports before the envoy listeners
+	// selecting it.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Listeners []Listener `json:"listeners,omitempty"`
+	// ServiceSelector picks the services in this namespace that builtIn xds exposes as clusters,
+	// nil exposes none
+	// +optional
+	ServiceSelector *metav1.LabelSelector `json:"serviceSelector,omitempty"`
+	// DeletionPolicy decides what happens to the generated deployment, service and config map
+	// when the envoy is deleted, defaults to Delete
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+type EnvoyDeployment struct {
+	// Name names the generated deployment and service, defaults to the envoy's name
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name,omitempty"`
+	// Replicas of the generated deployment, defaults to 1
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Rollout bounds how many envoy pods are replaced at once when the bootstrap or spec changes
+	// +optional
+	Rollout *EnvoyRollout `json:"rollout,omitempty"`
+	// Template describes the envoy pods
+	// +optional
+	Template EnvoyPodTemplate `json:"template"`
+}
+
+type EnvoyPodTemplate struct {
+	// Image is the envoy image of the fleet, defaults to the image configured for the controller
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image,omitempty"`
+	// Resources of the envoy container
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// NodeSelector and Tolerations place the envoy pods
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// EnvoyRollout is the rolling update strategy of the envoy deployment, both default to 25%
+type EnvoyRollout struct {
+	MaxSurge       *intstr.IntOrString `json:"maxSurge,omitempty"`
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+type EnvoyBootstrap struct {
+	// ConfigMapName names the generated config map, defaults to the envoy's name
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	ConfigMapName string `json:"configMapName,omitempty"`
+	// AdminPort is where the envoy admin interface listens on localhost, defaults to the port
+	// configured for the controller
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	AdminPort int32 `json:"adminPort,omitempty"`
+	// XDS defaults to the controller's builtIn xds server
+	// +optional
+	XDS EnvoyXDS `json:"xds"`
+	// Overrides is a JSON merge patch applied to the generated bootstrap, e.g. to add static
+	// clusters or tune the admin interface
+	// +optional
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Overrides *runtime.RawExtension `json:"overrides,omitempty"`
+}
+
+// Listener opens a port on the envoy it is declared on
+// +kubebuilder:validation:XValidation:rule="self.protocol != 'TCP' || has(self.backend)",message="TCP listeners need a backend"
+// +kubebuilder:validation:XValidation:rule="self.protocol != 'TLS' || has(self.tlsRoutes)",message="TLS listeners need at least one tlsRoute"
+type Listener struct {
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+	// Port is the container port envoy listens on
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+	// ServicePort is the port exposed on the envoy service, defaults to Port
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	ServicePort int32            `json:"servicePort,omitempty"`
+	Protocol    ListenerProtocol `json:"protocol"`
+	// +optional
+	Backend *Backend `json:"backend,omitempty"`
+	// +optional
+	// +kubebuilder:validation:MinItems=1
+	TLSRoutes []TLSRoute `json:"tlsRoutes,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=HTTP;TCP;TLS
+type ListenerProtocol string
+
+// TLSRoute sends TLS connections for ServerNames to Backend, an empty ServerNames matches any SNI
+type TLSRoute struct {
+	ServerNames []string `json:"serverNames,omitempty"`
+	Backend     Backend  `json:"backend"`
+}
+
+// Backend is a port of a service exposed to the envoy through its serviceSelector
+type Backend struct {
+	// +kubebuilder:validation:MinLength=1
+	Service string `json:"service"`
+	// Port is the number or name of a service port
+	Port intstr.IntOrString `json:"port"`
+	// Weight is the share of requests sent to this backend, backends all left without one split evenly
+	// +optional
+	Weight uint32 `json:"weight,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Delete;Orphan
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete lets the generated objects be garbage collected with the envoy
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyOrphan leaves the generated objects running, e.g. to hand a fleet over to a new envoy
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// EnvoyXDS is the management server a fleet fetches its configuration from
+// +kubebuilder:validation:XValidation:rule="(has(self.builtIn) && self.builtIn) || has(self.host) == has(self.port)",message="host and port are set together unless builtIn is set"
+type EnvoyXDS struct {
+	// ClusterName is the name of the xds cluster in the bootstrap, defaults to xds_cluster
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName,omitempty"`
+	// BuiltIn points the fleet at the controller's own xds server, Host and Port are ignored.
+	// It defaults to true when neither Host nor Port is set.
+	// +optional
+	BuiltIn bool `json:"builtIn,omitempty"`
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	Host string `json:"host,omitempty"`
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int `json:"port,omitempty"`
+}
+
+type EnvoyStatus struct {
+	// ObservedGeneration is the generation of the spec this status was computed for
+	ObservedGeneration int64            `json:"observedGeneration,omitempty"`
+	Conditions         []EnvoyCondition `json:"conditions,omitempty"`
+
+	AvailableReplicas int32 `json:"availableReplicas"`
+	// Replicas and UpdatedReplicas count all envoy pods and those running the current bootstrap
+	Replicas        int32        `json:"replicas,omitempty"`
+	UpdatedReplicas int32        `json:"updatedReplicas,omitempty"`
+	Rollout         RolloutState `json:"rollout,omitempty"`
+
+	// DeploymentName, ServiceName and ConfigMapName are the objects generated for this envoy
+	DeploymentName string `json:"deploymentName,omitempty"`
+	ServiceName    string `json:"serviceName,omitempty"`
+	ConfigMapName  string `json:"configMapName,omitempty"`
+	// ServiceAddress is the cluster ip of the envoy service
+	ServiceAddress string `json:"serviceAddress,omitempty"`
+	// BootstrapHash is the hash of the bootstrap in the config map, see the pod template annotation
+	BootstrapHash string `json:"bootstrapHash,omitempty"`
+}
+
+type EnvoyConditionType string
+
+type EnvoyCondition struct {
+	Type               EnvoyConditionType     `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
+}
+
+type RolloutState string
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type EnvoyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []Envoy `json:"items"`
+}
@@ -1,3 +0,0 @@
-// +k8s:deepcopy-gen=package,register
-// +groupName=example.com
-package v1
@@ -19,7 +19,8 @@ limitations under the License.
 package versioned
 
 import (
-	examplev1 "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/typed/example.com/v1"
+	envoyv1 "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/typed/envoy.starizard.io/v1"
+	envoyv1alpha2 "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/typed/envoy.starizard.io/v1alpha2"
 	discovery "k8s.io/client-go/discovery"
 	rest "k8s.io/client-go/rest"
 	flowcontrol "k8s.io/client-go/util/flowcontrol"
@@ -27,19 +28,26 @@ import (
 
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
-	ExampleV1() examplev1.ExampleV1Interface
+	EnvoyV1() envoyv1.EnvoyV1Interface
+	EnvoyV1alpha2() envoyv1alpha2.EnvoyV1alpha2Interface
 }
 
 // Clientset contains the clients for groups. Each group has exactly one
 // version included in a Clientset.
 type Clientset struct {
 	*discovery.DiscoveryClient
-	exampleV1 *examplev1.ExampleV1Client
+	envoyV1       *envoyv1.EnvoyV1Client
+	envoyV1alpha2 *envoyv1alpha2.EnvoyV1alpha2Client
 }
 
-// ExampleV1 retrieves the ExampleV1Client
-func (c *Clientset) ExampleV1() examplev1.ExampleV1Interface {
-	return c.exampleV1
+// EnvoyV1 retrieves the EnvoyV1Client
+func (c *Clientset) EnvoyV1() envoyv1.EnvoyV1Interface {
+	return c.envoyV1
+}
+
+// EnvoyV1alpha2 retrieves the EnvoyV1alpha2Client
+func (c *Clientset) EnvoyV1alpha2() envoyv1alpha2.EnvoyV1alpha2Interface {
+	return c.envoyV1alpha2
 }
 
 // Discovery retrieves the DiscoveryClient
@@ -58,7 +66,11 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 	}
 	var cs Clientset
 	var err error
-	cs.exampleV1, err = examplev1.NewForConfig(&configShallowCopy)
+	cs.envoyV1, err = envoyv1.NewForConfig(&configShallowCopy)
+	if err != nil {
+		return nil, err
+	}
+	cs.envoyV1alpha2, err = envoyv1alpha2.NewForConfig(&configShallowCopy)
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +86,8 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 // panics if there is an error in the config.
 func NewForConfigOrDie(c *rest.Config) *Clientset {
 	var cs Clientset
-	cs.exampleV1 = examplev1.NewForConfigOrDie(c)
+	cs.envoyV1 = envoyv1.NewForConfigOrDie(c)
+	cs.envoyV1alpha2 = envoyv1alpha2.NewForConfigOrDie(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClientForConfigOrDie(c)
 	return &cs
@@ -83,7 +96,8 @@ func NewForConfigOrDie(c *rest.Config) *Clientset {
 // New creates a new Clientset for the given RESTClient.
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
-	cs.exampleV1 = examplev1.New(c)
+	cs.envoyV1 = envoyv1.New(c)
+	cs.envoyV1alpha2 = envoyv1alpha2.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
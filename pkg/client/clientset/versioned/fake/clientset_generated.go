@@ -20,8 +20,10 @@ package fake
 
 import (
 	clientset "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned"
-	examplev1 "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/typed/example.com/v1"
-	fakeexamplev1 "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/typed/example.com/v1/fake"
+	envoyv1 "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/typed/envoy.starizard.io/v1"
+	fakeenvoyv1 "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/typed/envoy.starizard.io/v1/fake"
+	envoyv1alpha2 "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/typed/envoy.starizard.io/v1alpha2"
+	fakeenvoyv1alpha2 "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/typed/envoy.starizard.io/v1alpha2/fake"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/discovery"
@@ -76,7 +78,12 @@ func (c *Clientset) Tracker() testing.ObjectTracker {
 
 var _ clientset.Interface = &Clientset{}
 
-// ExampleV1 retrieves the ExampleV1Client
-func (c *Clientset) ExampleV1() examplev1.ExampleV1Interface {
-	return &fakeexamplev1.FakeExampleV1{Fake: &c.Fake}
+// EnvoyV1 retrieves the EnvoyV1Client
+func (c *Clientset) EnvoyV1() envoyv1.EnvoyV1Interface {
+	return &fakeenvoyv1.FakeEnvoyV1{Fake: &c.Fake}
+}
+
+// EnvoyV1alpha2 retrieves the EnvoyV1alpha2Client
+func (c *Clientset) EnvoyV1alpha2() envoyv1alpha2.EnvoyV1alpha2Interface {
+	return &fakeenvoyv1alpha2.FakeEnvoyV1alpha2{Fake: &c.Fake}
 }
@@ -19,7 +19,8 @@ limitations under the License.
 package fake
 
 import (
-	examplev1 "github.com/starizard/kube-envoy-controller/pkg/api/example.com/v1"
+	envoyv1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	envoyv1alpha2 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1alpha2"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -31,7 +32,8 @@ var scheme = runtime.NewScheme()
 var codecs = serializer.NewCodecFactory(scheme)
 var parameterCodec = runtime.NewParameterCodec(scheme)
 var localSchemeBuilder = runtime.SchemeBuilder{
-	examplev1.AddToScheme,
+	envoyv1.AddToScheme,
+	envoyv1alpha2.AddToScheme,
 }
 
 // AddToScheme adds all types of this clientset into the given scheme. This allows composition
@@ -21,7 +21,7 @@ package v1
 import (
 	"time"
 
-	v1 "github.com/starizard/kube-envoy-controller/pkg/api/example.com/v1"
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
 	scheme "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/scheme"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	types "k8s.io/apimachinery/pkg/types"
@@ -56,7 +56,7 @@ type envoys struct {
 }
 
 // newEnvoys returns a Envoys
-func newEnvoys(c *ExampleV1Client, namespace string) *envoys {
+func newEnvoys(c *EnvoyV1Client, namespace string) *envoys {
 	return &envoys{
 		client: c.RESTClient(),
 		ns:     namespace,
@@ -0,0 +1,174 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	"time"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	scheme "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/scheme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// EnvoyListenersGetter has a method to return a EnvoyListenerInterface.
+// A group's client should implement this interface.
+type EnvoyListenersGetter interface {
+	EnvoyListeners(namespace string) EnvoyListenerInterface
+}
+
+// EnvoyListenerInterface has methods to work with EnvoyListener resources.
+type EnvoyListenerInterface interface {
+	Create(*v1.EnvoyListener) (*v1.EnvoyListener, error)
+	Update(*v1.EnvoyListener) (*v1.EnvoyListener, error)
+	Delete(name string, options *metav1.DeleteOptions) error
+	DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error
+	Get(name string, options metav1.GetOptions) (*v1.EnvoyListener, error)
+	List(opts metav1.ListOptions) (*v1.EnvoyListenerList, error)
+	Watch(opts metav1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.EnvoyListener, err error)
+	EnvoyListenerExpansion
+}
+
+// envoyListeners implements EnvoyListenerInterface
+type envoyListeners struct {
+	client rest.Interface
+	ns     string
+}
+
+// newEnvoyListeners returns a EnvoyListeners
+func newEnvoyListeners(c *EnvoyV1Client, namespace string) *envoyListeners {
+	return &envoyListeners{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the envoyListener, and returns the corresponding envoyListener object, and an error if there is any.
+func (c *envoyListeners) Get(name string, options metav1.GetOptions) (result *v1.EnvoyListener, err error) {
+	result = &v1.EnvoyListener{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("envoylisteners").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of EnvoyListeners that match those selectors.
+func (c *envoyListeners) List(opts metav1.ListOptions) (result *v1.EnvoyListenerList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1.EnvoyListenerList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("envoylisteners").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested envoyListeners.
+func (c *envoyListeners) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("envoylisteners").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a envoyListener and creates it.  Returns the server's representation of the envoyListener, and an error, if there is any.
+func (c *envoyListeners) Create(envoyListener *v1.EnvoyListener) (result *v1.EnvoyListener, err error) {
+	result = &v1.EnvoyListener{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("envoylisteners").
+		Body(envoyListener).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a envoyListener and updates it. Returns the server's representation of the envoyListener, and an error, if there is any.
+func (c *envoyListeners) Update(envoyListener *v1.EnvoyListener) (result *v1.EnvoyListener, err error) {
+	result = &v1.EnvoyListener{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("envoylisteners").
+		Name(envoyListener.Name).
+		Body(envoyListener).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the envoyListener and deletes it. Returns an error if one occurs.
+func (c *envoyListeners) Delete(name string, options *metav1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("envoylisteners").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *envoyListeners) DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("envoylisteners").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched envoyListener.
+func (c *envoyListeners) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.EnvoyListener, err error) {
+	result = &v1.EnvoyListener{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("envoylisteners").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
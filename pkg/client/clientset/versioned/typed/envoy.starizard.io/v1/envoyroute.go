@@ -0,0 +1,191 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	"time"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	scheme "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/scheme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// EnvoyRoutesGetter has a method to return a EnvoyRouteInterface.
+// A group's client should implement this interface.
+type EnvoyRoutesGetter interface {
+	EnvoyRoutes(namespace string) EnvoyRouteInterface
+}
+
+// EnvoyRouteInterface has methods to work with EnvoyRoute resources.
+type EnvoyRouteInterface interface {
+	Create(*v1.EnvoyRoute) (*v1.EnvoyRoute, error)
+	Update(*v1.EnvoyRoute) (*v1.EnvoyRoute, error)
+	UpdateStatus(*v1.EnvoyRoute) (*v1.EnvoyRoute, error)
+	Delete(name string, options *metav1.DeleteOptions) error
+	DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error
+	Get(name string, options metav1.GetOptions) (*v1.EnvoyRoute, error)
+	List(opts metav1.ListOptions) (*v1.EnvoyRouteList, error)
+	Watch(opts metav1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.EnvoyRoute, err error)
+	EnvoyRouteExpansion
+}
+
+// envoyRoutes implements EnvoyRouteInterface
+type envoyRoutes struct {
+	client rest.Interface
+	ns     string
+}
+
+// newEnvoyRoutes returns a EnvoyRoutes
+func newEnvoyRoutes(c *EnvoyV1Client, namespace string) *envoyRoutes {
+	return &envoyRoutes{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the envoyRoute, and returns the corresponding envoyRoute object, and an error if there is any.
+func (c *envoyRoutes) Get(name string, options metav1.GetOptions) (result *v1.EnvoyRoute, err error) {
+	result = &v1.EnvoyRoute{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("envoyroutes").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of EnvoyRoutes that match those selectors.
+func (c *envoyRoutes) List(opts metav1.ListOptions) (result *v1.EnvoyRouteList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1.EnvoyRouteList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("envoyroutes").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested envoyRoutes.
+func (c *envoyRoutes) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("envoyroutes").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a envoyRoute and creates it.  Returns the server's representation of the envoyRoute, and an error, if there is any.
+func (c *envoyRoutes) Create(envoyRoute *v1.EnvoyRoute) (result *v1.EnvoyRoute, err error) {
+	result = &v1.EnvoyRoute{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("envoyroutes").
+		Body(envoyRoute).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a envoyRoute and updates it. Returns the server's representation of the envoyRoute, and an error, if there is any.
+func (c *envoyRoutes) Update(envoyRoute *v1.EnvoyRoute) (result *v1.EnvoyRoute, err error) {
+	result = &v1.EnvoyRoute{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("envoyroutes").
+		Name(envoyRoute.Name).
+		Body(envoyRoute).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *envoyRoutes) UpdateStatus(envoyRoute *v1.EnvoyRoute) (result *v1.EnvoyRoute, err error) {
+	result = &v1.EnvoyRoute{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("envoyroutes").
+		Name(envoyRoute.Name).
+		SubResource("status").
+		Body(envoyRoute).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the envoyRoute and deletes it. Returns an error if one occurs.
+func (c *envoyRoutes) Delete(name string, options *metav1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("envoyroutes").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *envoyRoutes) DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("envoyroutes").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched envoyRoute.
+func (c *envoyRoutes) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.EnvoyRoute, err error) {
+	result = &v1.EnvoyRoute{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("envoyroutes").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
@@ -0,0 +1,128 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	envoystarizardiov1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeEnvoyListeners implements EnvoyListenerInterface
+type FakeEnvoyListeners struct {
+	Fake *FakeEnvoyV1
+	ns   string
+}
+
+var envoylistenersResource = schema.GroupVersionResource{Group: "envoy.starizard.io", Version: "v1", Resource: "envoylisteners"}
+
+var envoylistenersKind = schema.GroupVersionKind{Group: "envoy.starizard.io", Version: "v1", Kind: "EnvoyListener"}
+
+// Get takes name of the envoyListener, and returns the corresponding envoyListener object, and an error if there is any.
+func (c *FakeEnvoyListeners) Get(name string, options v1.GetOptions) (result *envoystarizardiov1.EnvoyListener, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(envoylistenersResource, c.ns, name), &envoystarizardiov1.EnvoyListener{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*envoystarizardiov1.EnvoyListener), err
+}
+
+// List takes label and field selectors, and returns the list of EnvoyListeners that match those selectors.
+func (c *FakeEnvoyListeners) List(opts v1.ListOptions) (result *envoystarizardiov1.EnvoyListenerList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(envoylistenersResource, envoylistenersKind, c.ns, opts), &envoystarizardiov1.EnvoyListenerList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &envoystarizardiov1.EnvoyListenerList{ListMeta: obj.(*envoystarizardiov1.EnvoyListenerList).ListMeta}
+	for _, item := range obj.(*envoystarizardiov1.EnvoyListenerList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested envoyListeners.
+func (c *FakeEnvoyListeners) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(envoylistenersResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a envoyListener and creates it.  Returns the server's representation of the envoyListener, and an error, if there is any.
+func (c *FakeEnvoyListeners) Create(envoyListener *envoystarizardiov1.EnvoyListener) (result *envoystarizardiov1.EnvoyListener, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(envoylistenersResource, c.ns, envoyListener), &envoystarizardiov1.EnvoyListener{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*envoystarizardiov1.EnvoyListener), err
+}
+
+// Update takes the representation of a envoyListener and updates it. Returns the server's representation of the envoyListener, and an error, if there is any.
+func (c *FakeEnvoyListeners) Update(envoyListener *envoystarizardiov1.EnvoyListener) (result *envoystarizardiov1.EnvoyListener, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(envoylistenersResource, c.ns, envoyListener), &envoystarizardiov1.EnvoyListener{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*envoystarizardiov1.EnvoyListener), err
+}
+
+// Delete takes name of the envoyListener and deletes it. Returns an error if one occurs.
+func (c *FakeEnvoyListeners) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(envoylistenersResource, c.ns, name), &envoystarizardiov1.EnvoyListener{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeEnvoyListeners) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(envoylistenersResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &envoystarizardiov1.EnvoyListenerList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched envoyListener.
+func (c *FakeEnvoyListeners) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *envoystarizardiov1.EnvoyListener, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(envoylistenersResource, c.ns, name, pt, data, subresources...), &envoystarizardiov1.EnvoyListener{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*envoystarizardiov1.EnvoyListener), err
+}
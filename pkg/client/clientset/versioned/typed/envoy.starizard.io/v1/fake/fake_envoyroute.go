@@ -0,0 +1,140 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	envoystarizardiov1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeEnvoyRoutes implements EnvoyRouteInterface
+type FakeEnvoyRoutes struct {
+	Fake *FakeEnvoyV1
+	ns   string
+}
+
+var envoyroutesResource = schema.GroupVersionResource{Group: "envoy.starizard.io", Version: "v1", Resource: "envoyroutes"}
+
+var envoyroutesKind = schema.GroupVersionKind{Group: "envoy.starizard.io", Version: "v1", Kind: "EnvoyRoute"}
+
+// Get takes name of the envoyRoute, and returns the corresponding envoyRoute object, and an error if there is any.
+func (c *FakeEnvoyRoutes) Get(name string, options v1.GetOptions) (result *envoystarizardiov1.EnvoyRoute, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(envoyroutesResource, c.ns, name), &envoystarizardiov1.EnvoyRoute{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*envoystarizardiov1.EnvoyRoute), err
+}
+
+// List takes label and field selectors, and returns the list of EnvoyRoutes that match those selectors.
+func (c *FakeEnvoyRoutes) List(opts v1.ListOptions) (result *envoystarizardiov1.EnvoyRouteList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(envoyroutesResource, envoyroutesKind, c.ns, opts), &envoystarizardiov1.EnvoyRouteList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &envoystarizardiov1.EnvoyRouteList{ListMeta: obj.(*envoystarizardiov1.EnvoyRouteList).ListMeta}
+	for _, item := range obj.(*envoystarizardiov1.EnvoyRouteList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested envoyRoutes.
+func (c *FakeEnvoyRoutes) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(envoyroutesResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a envoyRoute and creates it.  Returns the server's representation of the envoyRoute, and an error, if there is any.
+func (c *FakeEnvoyRoutes) Create(envoyRoute *envoystarizardiov1.EnvoyRoute) (result *envoystarizardiov1.EnvoyRoute, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(envoyroutesResource, c.ns, envoyRoute), &envoystarizardiov1.EnvoyRoute{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*envoystarizardiov1.EnvoyRoute), err
+}
+
+// Update takes the representation of a envoyRoute and updates it. Returns the server's representation of the envoyRoute, and an error, if there is any.
+func (c *FakeEnvoyRoutes) Update(envoyRoute *envoystarizardiov1.EnvoyRoute) (result *envoystarizardiov1.EnvoyRoute, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(envoyroutesResource, c.ns, envoyRoute), &envoystarizardiov1.EnvoyRoute{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*envoystarizardiov1.EnvoyRoute), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeEnvoyRoutes) UpdateStatus(envoyRoute *envoystarizardiov1.EnvoyRoute) (*envoystarizardiov1.EnvoyRoute, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(envoyroutesResource, "status", c.ns, envoyRoute), &envoystarizardiov1.EnvoyRoute{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*envoystarizardiov1.EnvoyRoute), err
+}
+
+// Delete takes name of the envoyRoute and deletes it. Returns an error if one occurs.
+func (c *FakeEnvoyRoutes) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(envoyroutesResource, c.ns, name), &envoystarizardiov1.EnvoyRoute{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeEnvoyRoutes) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(envoyroutesResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &envoystarizardiov1.EnvoyRouteList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched envoyRoute.
+func (c *FakeEnvoyRoutes) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *envoystarizardiov1.EnvoyRoute, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(envoyroutesResource, c.ns, name, pt, data, subresources...), &envoystarizardiov1.EnvoyRoute{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*envoystarizardiov1.EnvoyRoute), err
+}
@@ -16,32 +16,32 @@ limitations under the License.
 
 // Code generated by client-gen. DO NOT EDIT.
 
-package v1
+package v1alpha2
 
 import (
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	rest "k8s.io/client-go/rest"
 
-	v1 "github.com/starizard/kube-envoy-controller/pkg/api/example.com/v1"
+	v1alpha2 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1alpha2"
 	"github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/scheme"
 )
 
-type ExampleV1Interface interface {
+type EnvoyV1alpha2Interface interface {
 	RESTClient() rest.Interface
 	EnvoysGetter
 }
 
-// ExampleV1Client is used to interact with features provided by the example.com group.
-type ExampleV1Client struct {
+// EnvoyV1alpha2Client is used to interact with features provided by the envoy.starizard.io group.
+type EnvoyV1alpha2Client struct {
 	restClient rest.Interface
 }
 
-func (c *ExampleV1Client) Envoys(namespace string) EnvoyInterface {
+func (c *EnvoyV1alpha2Client) Envoys(namespace string) EnvoyInterface {
 	return newEnvoys(c, namespace)
 }
 
-// NewForConfig creates a new ExampleV1Client for the given config.
-func NewForConfig(c *rest.Config) (*ExampleV1Client, error) {
+// NewForConfig creates a new EnvoyV1alpha2Client for the given config.
+func NewForConfig(c *rest.Config) (*EnvoyV1alpha2Client, error) {
 	config := *c
 	if err := setConfigDefaults(&config); err != nil {
 		return nil, err
@@ -50,12 +50,12 @@ func NewForConfig(c *rest.Config) (*ExampleV1Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ExampleV1Client{client}, nil
+	return &EnvoyV1alpha2Client{client}, nil
 }
 
-// NewForConfigOrDie creates a new ExampleV1Client for the given config and
+// NewForConfigOrDie creates a new EnvoyV1alpha2Client for the given config and
 // panics if there is an error in the config.
-func NewForConfigOrDie(c *rest.Config) *ExampleV1Client {
+func NewForConfigOrDie(c *rest.Config) *EnvoyV1alpha2Client {
 	client, err := NewForConfig(c)
 	if err != nil {
 		panic(err)
@@ -63,13 +63,13 @@ func NewForConfigOrDie(c *rest.Config) *ExampleV1Client {
 	return client
 }
 
-// New creates a new ExampleV1Client for the given RESTClient.
-func New(c rest.Interface) *ExampleV1Client {
-	return &ExampleV1Client{c}
+// New creates a new EnvoyV1alpha2Client for the given RESTClient.
+func New(c rest.Interface) *EnvoyV1alpha2Client {
+	return &EnvoyV1alpha2Client{c}
 }
 
 func setConfigDefaults(config *rest.Config) error {
-	gv := v1.SchemeGroupVersion
+	gv := v1alpha2.SchemeGroupVersion
 	config.GroupVersion = &gv
 	config.APIPath = "/apis"
 
@@ -85,7 +85,7 @@ func setConfigDefaults(config *rest.Config) error {
 
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
-func (c *ExampleV1Client) RESTClient() rest.Interface {
+func (c *EnvoyV1alpha2Client) RESTClient() rest.Interface {
 	if c == nil {
 		return nil
 	}
@@ -19,7 +19,7 @@ limitations under the License.
 package fake
 
 import (
-	examplecomv1 "github.com/starizard/kube-envoy-controller/pkg/api/example.com/v1"
+	v1alpha2 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1alpha2"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	labels "k8s.io/apimachinery/pkg/labels"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -30,29 +30,29 @@ import (
 
 // FakeEnvoys implements EnvoyInterface
 type FakeEnvoys struct {
-	Fake *FakeExampleV1
+	Fake *FakeEnvoyV1alpha2
 	ns   string
 }
 
-var envoysResource = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "envoys"}
+var envoysResource = schema.GroupVersionResource{Group: "envoy.starizard.io", Version: "v1alpha2", Resource: "envoys"}
 
-var envoysKind = schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Envoy"}
+var envoysKind = schema.GroupVersionKind{Group: "envoy.starizard.io", Version: "v1alpha2", Kind: "Envoy"}
 
 // Get takes name of the envoy, and returns the corresponding envoy object, and an error if there is any.
-func (c *FakeEnvoys) Get(name string, options v1.GetOptions) (result *examplecomv1.Envoy, err error) {
+func (c *FakeEnvoys) Get(name string, options v1.GetOptions) (result *v1alpha2.Envoy, err error) {
 	obj, err := c.Fake.
-		Invokes(testing.NewGetAction(envoysResource, c.ns, name), &examplecomv1.Envoy{})
+		Invokes(testing.NewGetAction(envoysResource, c.ns, name), &v1alpha2.Envoy{})
 
 	if obj == nil {
 		return nil, err
 	}
-	return obj.(*examplecomv1.Envoy), err
+	return obj.(*v1alpha2.Envoy), err
 }
 
 // List takes label and field selectors, and returns the list of Envoys that match those selectors.
-func (c *FakeEnvoys) List(opts v1.ListOptions) (result *examplecomv1.EnvoyList, err error) {
+func (c *FakeEnvoys) List(opts v1.ListOptions) (result *v1alpha2.EnvoyList, err error) {
 	obj, err := c.Fake.
-		Invokes(testing.NewListAction(envoysResource, envoysKind, c.ns, opts), &examplecomv1.EnvoyList{})
+		Invokes(testing.NewListAction(envoysResource, envoysKind, c.ns, opts), &v1alpha2.EnvoyList{})
 
 	if obj == nil {
 		return nil, err
@@ -62,8 +62,8 @@ func (c *FakeEnvoys) List(opts v1.ListOptions) (result *examplecomv1.EnvoyList,
 	if label == nil {
 		label = labels.Everything()
 	}
-	list := &examplecomv1.EnvoyList{ListMeta: obj.(*examplecomv1.EnvoyList).ListMeta}
-	for _, item := range obj.(*examplecomv1.EnvoyList).Items {
+	list := &v1alpha2.EnvoyList{ListMeta: obj.(*v1alpha2.EnvoyList).ListMeta}
+	for _, item := range obj.(*v1alpha2.EnvoyList).Items {
 		if label.Matches(labels.Set(item.Labels)) {
 			list.Items = append(list.Items, item)
 		}
@@ -79,43 +79,43 @@ func (c *FakeEnvoys) Watch(opts v1.ListOptions) (watch.Interface, error) {
 }
 
 // Create takes the representation of a envoy and creates it.  Returns the server's representation of the envoy, and an error, if there is any.
-func (c *FakeEnvoys) Create(envoy *examplecomv1.Envoy) (result *examplecomv1.Envoy, err error) {
+func (c *FakeEnvoys) Create(envoy *v1alpha2.Envoy) (result *v1alpha2.Envoy, err error) {
 	obj, err := c.Fake.
-		Invokes(testing.NewCreateAction(envoysResource, c.ns, envoy), &examplecomv1.Envoy{})
+		Invokes(testing.NewCreateAction(envoysResource, c.ns, envoy), &v1alpha2.Envoy{})
 
 	if obj == nil {
 		return nil, err
 	}
-	return obj.(*examplecomv1.Envoy), err
+	return obj.(*v1alpha2.Envoy), err
 }
 
 // Update takes the representation of a envoy and updates it. Returns the server's representation of the envoy, and an error, if there is any.
-func (c *FakeEnvoys) Update(envoy *examplecomv1.Envoy) (result *examplecomv1.Envoy, err error) {
+func (c *FakeEnvoys) Update(envoy *v1alpha2.Envoy) (result *v1alpha2.Envoy, err error) {
 	obj, err := c.Fake.
-		Invokes(testing.NewUpdateAction(envoysResource, c.ns, envoy), &examplecomv1.Envoy{})
+		Invokes(testing.NewUpdateAction(envoysResource, c.ns, envoy), &v1alpha2.Envoy{})
 
 	if obj == nil {
 		return nil, err
 	}
-	return obj.(*examplecomv1.Envoy), err
+	return obj.(*v1alpha2.Envoy), err
 }
 
 // UpdateStatus was generated because the type contains a Status member.
 // Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
-func (c *FakeEnvoys) UpdateStatus(envoy *examplecomv1.Envoy) (*examplecomv1.Envoy, error) {
+func (c *FakeEnvoys) UpdateStatus(envoy *v1alpha2.Envoy) (*v1alpha2.Envoy, error) {
 	obj, err := c.Fake.
-		Invokes(testing.NewUpdateSubresourceAction(envoysResource, "status", c.ns, envoy), &examplecomv1.Envoy{})
+		Invokes(testing.NewUpdateSubresourceAction(envoysResource, "status", c.ns, envoy), &v1alpha2.Envoy{})
 
 	if obj == nil {
 		return nil, err
 	}
-	return obj.(*examplecomv1.Envoy), err
+	return obj.(*v1alpha2.Envoy), err
 }
 
 // Delete takes name of the envoy and deletes it. Returns an error if one occurs.
 func (c *FakeEnvoys) Delete(name string, options *v1.DeleteOptions) error {
 	_, err := c.Fake.
-		Invokes(testing.NewDeleteAction(envoysResource, c.ns, name), &examplecomv1.Envoy{})
+		Invokes(testing.NewDeleteAction(envoysResource, c.ns, name), &v1alpha2.Envoy{})
 
 	return err
 }
@@ -124,17 +124,17 @@ func (c *FakeEnvoys) Delete(name string, options *v1.DeleteOptions) error {
 func (c *FakeEnvoys) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
 	action := testing.NewDeleteCollectionAction(envoysResource, c.ns, listOptions)
 
-	_, err := c.Fake.Invokes(action, &examplecomv1.EnvoyList{})
+	_, err := c.Fake.Invokes(action, &v1alpha2.EnvoyList{})
 	return err
 }
 
 // Patch applies the patch and returns the patched envoy.
-func (c *FakeEnvoys) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *examplecomv1.Envoy, err error) {
+func (c *FakeEnvoys) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha2.Envoy, err error) {
 	obj, err := c.Fake.
-		Invokes(testing.NewPatchSubresourceAction(envoysResource, c.ns, name, pt, data, subresources...), &examplecomv1.Envoy{})
+		Invokes(testing.NewPatchSubresourceAction(envoysResource, c.ns, name, pt, data, subresources...), &v1alpha2.Envoy{})
 
 	if obj == nil {
 		return nil, err
 	}
-	return obj.(*examplecomv1.Envoy), err
+	return obj.(*v1alpha2.Envoy), err
 }
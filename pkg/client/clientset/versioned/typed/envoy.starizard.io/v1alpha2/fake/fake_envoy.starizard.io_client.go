@@ -19,22 +19,22 @@ limitations under the License.
 package fake
 
 import (
-	v1 "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/typed/example.com/v1"
+	v1alpha2 "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/typed/envoy.starizard.io/v1alpha2"
 	rest "k8s.io/client-go/rest"
 	testing "k8s.io/client-go/testing"
 )
 
-type FakeExampleV1 struct {
+type FakeEnvoyV1alpha2 struct {
 	*testing.Fake
 }
 
-func (c *FakeExampleV1) Envoys(namespace string) v1.EnvoyInterface {
+func (c *FakeEnvoyV1alpha2) Envoys(namespace string) v1alpha2.EnvoyInterface {
 	return &FakeEnvoys{c, namespace}
 }
 
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
-func (c *FakeExampleV1) RESTClient() rest.Interface {
+func (c *FakeEnvoyV1alpha2) RESTClient() rest.Interface {
 	var ret *rest.RESTClient
 	return ret
 }
@@ -16,6 +16,6 @@ limitations under the License.
 
 // Code generated by client-gen. DO NOT EDIT.
 
-package v1
+package v1alpha2
 
 type EnvoyExpansion interface{}
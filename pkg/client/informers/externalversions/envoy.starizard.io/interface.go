@@ -16,10 +16,11 @@ limitations under the License.
 
 // Code generated by informer-gen. DO NOT EDIT.
 
-package example
+package envoy
 
 import (
-	v1 "github.com/starizard/kube-envoy-controller/pkg/client/informers/externalversions/example.com/v1"
+	v1 "github.com/starizard/kube-envoy-controller/pkg/client/informers/externalversions/envoy.starizard.io/v1"
+	v1alpha2 "github.com/starizard/kube-envoy-controller/pkg/client/informers/externalversions/envoy.starizard.io/v1alpha2"
 	internalinterfaces "github.com/starizard/kube-envoy-controller/pkg/client/informers/externalversions/internalinterfaces"
 )
 
@@ -27,6 +28,8 @@ import (
 type Interface interface {
 	// V1 provides access to shared informers for resources in V1.
 	V1() v1.Interface
+	// V1alpha2 provides access to shared informers for resources in V1alpha2.
+	V1alpha2() v1alpha2.Interface
 }
 
 type group struct {
@@ -44,3 +47,8 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (g *group) V1() v1.Interface {
 	return v1.New(g.factory, g.namespace, g.tweakListOptions)
 }
+
+// V1alpha2 returns a new v1alpha2.Interface.
+func (g *group) V1alpha2() v1alpha2.Interface {
+	return v1alpha2.New(g.factory, g.namespace, g.tweakListOptions)
+}
@@ -21,10 +21,10 @@ package v1
 import (
 	time "time"
 
-	examplecomv1 "github.com/starizard/kube-envoy-controller/pkg/api/example.com/v1"
+	envoystarizardiov1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
 	versioned "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned"
 	internalinterfaces "github.com/starizard/kube-envoy-controller/pkg/client/informers/externalversions/internalinterfaces"
-	v1 "github.com/starizard/kube-envoy-controller/pkg/client/listers/example.com/v1"
+	v1 "github.com/starizard/kube-envoy-controller/pkg/client/listers/envoy.starizard.io/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	watch "k8s.io/apimachinery/pkg/watch"
@@ -61,16 +61,16 @@ func NewFilteredEnvoyInformer(client versioned.Interface, namespace string, resy
 				if tweakListOptions != nil {
 					tweakListOptions(&options)
 				}
-				return client.ExampleV1().Envoys(namespace).List(options)
+				return client.EnvoyV1().Envoys(namespace).List(options)
 			},
 			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
 				if tweakListOptions != nil {
 					tweakListOptions(&options)
 				}
-				return client.ExampleV1().Envoys(namespace).Watch(options)
+				return client.EnvoyV1().Envoys(namespace).Watch(options)
 			},
 		},
-		&examplecomv1.Envoy{},
+		&envoystarizardiov1.Envoy{},
 		resyncPeriod,
 		indexers,
 	)
@@ -81,7 +81,7 @@ func (f *envoyInformer) defaultInformer(client versioned.Interface, resyncPeriod
 }
 
 func (f *envoyInformer) Informer() cache.SharedIndexInformer {
-	return f.factory.InformerFor(&examplecomv1.Envoy{}, f.defaultInformer)
+	return f.factory.InformerFor(&envoystarizardiov1.Envoy{}, f.defaultInformer)
 }
 
 func (f *envoyInformer) Lister() v1.EnvoyLister {
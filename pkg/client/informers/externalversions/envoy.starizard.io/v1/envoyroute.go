@@ -0,0 +1,89 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	time "time"
+
+	envoystarizardiov1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	versioned "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned"
+	internalinterfaces "github.com/starizard/kube-envoy-controller/pkg/client/informers/externalversions/internalinterfaces"
+	v1 "github.com/starizard/kube-envoy-controller/pkg/client/listers/envoy.starizard.io/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// EnvoyRouteInformer provides access to a shared informer and lister for
+// EnvoyRoutes.
+type EnvoyRouteInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1.EnvoyRouteLister
+}
+
+type envoyRouteInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewEnvoyRouteInformer constructs a new informer for EnvoyRoute type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewEnvoyRouteInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredEnvoyRouteInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredEnvoyRouteInformer constructs a new informer for EnvoyRoute type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredEnvoyRouteInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.EnvoyV1().EnvoyRoutes(namespace).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.EnvoyV1().EnvoyRoutes(namespace).Watch(options)
+			},
+		},
+		&envoystarizardiov1.EnvoyRoute{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *envoyRouteInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredEnvoyRouteInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *envoyRouteInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&envoystarizardiov1.EnvoyRoute{}, f.defaultInformer)
+}
+
+func (f *envoyRouteInformer) Lister() v1.EnvoyRouteLister {
+	return v1.NewEnvoyRouteLister(f.Informer().GetIndexer())
+}
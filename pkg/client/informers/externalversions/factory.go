@@ -24,7 +24,7 @@ import (
 	time "time"
 
 	versioned "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned"
-	examplecom "github.com/starizard/kube-envoy-controller/pkg/client/informers/externalversions/example.com"
+	envoystarizardio "github.com/starizard/kube-envoy-controller/pkg/client/informers/externalversions/envoy.starizard.io"
 	internalinterfaces "github.com/starizard/kube-envoy-controller/pkg/client/informers/externalversions/internalinterfaces"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
@@ -172,9 +172,9 @@ type SharedInformerFactory interface {
 	ForResource(resource schema.GroupVersionResource) (GenericInformer, error)
 	WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool
 
-	Example() examplecom.Interface
+	Envoy() envoystarizardio.Interface
 }
 
-func (f *sharedInformerFactory) Example() examplecom.Interface {
-	return examplecom.New(f, f.namespace, f.tweakListOptions)
+func (f *sharedInformerFactory) Envoy() envoystarizardio.Interface {
+	return envoystarizardio.New(f, f.namespace, f.tweakListOptions)
 }
@@ -21,7 +21,8 @@ package externalversions
 import (
 	"fmt"
 
-	v1 "github.com/starizard/kube-envoy-controller/pkg/api/example.com/v1"
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	v1alpha2 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1alpha2"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
 )
@@ -52,9 +53,17 @@ func (f *genericInformer) Lister() cache.GenericLister {
 // TODO extend this to unknown resources with a client pool
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
-	// Group=example.com, Version=v1
+	// Group=envoy.starizard.io, Version=v1
 	case v1.SchemeGroupVersion.WithResource("envoys"):
-		return &genericInformer{resource: resource.GroupResource(), informer: f.Example().V1().Envoys().Informer()}, nil
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Envoy().V1().Envoys().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("envoylisteners"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Envoy().V1().EnvoyListeners().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("envoyroutes"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Envoy().V1().EnvoyRoutes().Informer()}, nil
+
+		// Group=envoy.starizard.io, Version=v1alpha2
+	case v1alpha2.SchemeGroupVersion.WithResource("envoys"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Envoy().V1alpha2().Envoys().Informer()}, nil
 
 	}
 
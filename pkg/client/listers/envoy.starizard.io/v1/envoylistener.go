@@ -0,0 +1,94 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// EnvoyListenerLister helps list EnvoyListeners.
+type EnvoyListenerLister interface {
+	// List lists all EnvoyListeners in the indexer.
+	List(selector labels.Selector) (ret []*v1.EnvoyListener, err error)
+	// EnvoyListeners returns an object that can list and get EnvoyListeners.
+	EnvoyListeners(namespace string) EnvoyListenerNamespaceLister
+	EnvoyListenerListerExpansion
+}
+
+// envoyListenerLister implements the EnvoyListenerLister interface.
+type envoyListenerLister struct {
+	indexer cache.Indexer
+}
+
+// NewEnvoyListenerLister returns a new EnvoyListenerLister.
+func NewEnvoyListenerLister(indexer cache.Indexer) EnvoyListenerLister {
+	return &envoyListenerLister{indexer: indexer}
+}
+
+// List lists all EnvoyListeners in the indexer.
+func (s *envoyListenerLister) List(selector labels.Selector) (ret []*v1.EnvoyListener, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.EnvoyListener))
+	})
+	return ret, err
+}
+
+// EnvoyListeners returns an object that can list and get EnvoyListeners.
+func (s *envoyListenerLister) EnvoyListeners(namespace string) EnvoyListenerNamespaceLister {
+	return envoyListenerNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// EnvoyListenerNamespaceLister helps list and get EnvoyListeners.
+type EnvoyListenerNamespaceLister interface {
+	// List lists all EnvoyListeners in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1.EnvoyListener, err error)
+	// Get retrieves the EnvoyListener from the indexer for a given namespace and name.
+	Get(name string) (*v1.EnvoyListener, error)
+	EnvoyListenerNamespaceListerExpansion
+}
+
+// envoyListenerNamespaceLister implements the EnvoyListenerNamespaceLister
+// interface.
+type envoyListenerNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all EnvoyListeners in the indexer for a given namespace.
+func (s envoyListenerNamespaceLister) List(selector labels.Selector) (ret []*v1.EnvoyListener, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.EnvoyListener))
+	})
+	return ret, err
+}
+
+// Get retrieves the EnvoyListener from the indexer for a given namespace and name.
+func (s envoyListenerNamespaceLister) Get(name string) (*v1.EnvoyListener, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1.Resource("envoylistener"), name)
+	}
+	return obj.(*v1.EnvoyListener), nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// EnvoyRouteLister helps list EnvoyRoutes.
+type EnvoyRouteLister interface {
+	// List lists all EnvoyRoutes in the indexer.
+	List(selector labels.Selector) (ret []*v1.EnvoyRoute, err error)
+	// EnvoyRoutes returns an object that can list and get EnvoyRoutes.
+	EnvoyRoutes(namespace string) EnvoyRouteNamespaceLister
+	EnvoyRouteListerExpansion
+}
+
+// envoyRouteLister implements the EnvoyRouteLister interface.
+type envoyRouteLister struct {
+	indexer cache.Indexer
+}
+
+// NewEnvoyRouteLister returns a new EnvoyRouteLister.
+func NewEnvoyRouteLister(indexer cache.Indexer) EnvoyRouteLister {
+	return &envoyRouteLister{indexer: indexer}
+}
+
+// List lists all EnvoyRoutes in the indexer.
+func (s *envoyRouteLister) List(selector labels.Selector) (ret []*v1.EnvoyRoute, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.EnvoyRoute))
+	})
+	return ret, err
+}
+
+// EnvoyRoutes returns an object that can list and get EnvoyRoutes.
+func (s *envoyRouteLister) EnvoyRoutes(namespace string) EnvoyRouteNamespaceLister {
+	return envoyRouteNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// EnvoyRouteNamespaceLister helps list and get EnvoyRoutes.
+type EnvoyRouteNamespaceLister interface {
+	// List lists all EnvoyRoutes in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1.EnvoyRoute, err error)
+	// Get retrieves the EnvoyRoute from the indexer for a given namespace and name.
+	Get(name string) (*v1.EnvoyRoute, error)
+	EnvoyRouteNamespaceListerExpansion
+}
+
+// envoyRouteNamespaceLister implements the EnvoyRouteNamespaceLister
+// interface.
+type envoyRouteNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all EnvoyRoutes in the indexer for a given namespace.
+func (s envoyRouteNamespaceLister) List(selector labels.Selector) (ret []*v1.EnvoyRoute, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.EnvoyRoute))
+	})
+	return ret, err
+}
+
+// Get retrieves the EnvoyRoute from the indexer for a given namespace and name.
+func (s envoyRouteNamespaceLister) Get(name string) (*v1.EnvoyRoute, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1.Resource("envoyroute"), name)
+	}
+	return obj.(*v1.EnvoyRoute), nil
+}
@@ -0,0 +1,43 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1
+
+// EnvoyListerExpansion allows custom methods to be added to
+// EnvoyLister.
+type EnvoyListerExpansion interface{}
+
+// EnvoyNamespaceListerExpansion allows custom methods to be added to
+// EnvoyNamespaceLister.
+type EnvoyNamespaceListerExpansion interface{}
+
+// EnvoyListenerListerExpansion allows custom methods to be added to
+// EnvoyListenerLister.
+type EnvoyListenerListerExpansion interface{}
+
+// EnvoyListenerNamespaceListerExpansion allows custom methods to be added to
+// EnvoyListenerNamespaceLister.
+type EnvoyListenerNamespaceListerExpansion interface{}
+
+// EnvoyRouteListerExpansion allows custom methods to be added to
+// EnvoyRouteLister.
+type EnvoyRouteListerExpansion interface{}
+
+// EnvoyRouteNamespaceListerExpansion allows custom methods to be added to
+// EnvoyRouteNamespaceLister.
+type EnvoyRouteNamespaceListerExpansion interface{}
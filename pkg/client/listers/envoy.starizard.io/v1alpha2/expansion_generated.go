@@ -16,7 +16,7 @@ limitations under the License.
 
 // Code generated by lister-gen. DO NOT EDIT.
 
-package v1
+package v1alpha2
 
 // EnvoyListerExpansion allows custom methods to be added to
 // EnvoyLister.
@@ -0,0 +1,468 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	"github.com/starizard/kube-envoy-controller/pkg/logging"
+)
+
+// Config holds the controller settings. They are read, each overriding the previous, from the
+// defaults, the --config file (YAML or JSON), the environment and the command line.
+type Config struct {
+	// Kubeconfig and Master locate the API server, the in-cluster config is used when both are empty
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	Master     string `json:"master,omitempty"`
+	// Namespaces limits the controller to these namespaces, all namespaces when empty
+	Namespaces []string `json:"namespaces,omitempty"`
+	// LabelSelector limits the controller to the envoys, routes and listeners it matches
+	LabelSelector string `json:"labelSelector,omitempty"`
+
+	Resync          metav1.Duration `json:"resync"`
+	Workers         int             `json:"workers"`
+	MaxRetries      int             `json:"maxRetries"`
+	RetryBaseDelay  metav1.Duration `json:"retryBaseDelay"`
+	RetryMaxDelay   metav1.Duration `json:"retryMaxDelay"`
+	ShutdownTimeout metav1.Duration `json:"shutdownTimeout"`
+
+	Log            LogConfig            `json:"log"`
+	Envoy          EnvoyConfig          `json:"envoy"`
+	XDS            XDSConfig            `json:"xds"`
+	Webhook        WebhookConfig        `json:"webhook"`
+	Metrics        MetricsConfig        `json:"metrics"`
+	Health         HealthConfig         `json:"health"`
+	LeaderElection LeaderElectionConfig `json:"leaderElection"`
+	Migration      MigrationConfig      `json:"migration"`
+}
+
+// LogConfig is how the controller logs. Rendered bootstraps and other large payloads are only logged at debug.
+type LogConfig struct {
+	// Format is text or json
+	Format string `json:"format"`
+	// Level is debug, info, warn or error
+	Level string `json:"level"`
+}
+
+// EnvoyConfig is what the generated envoy deployments run
+type EnvoyConfig struct {
+	Image     string `json:"image"`
+	AdminPort int    `json:"adminPort"`
+}
+
+// XDSConfig is the embedded xds server and how builtIn envoys reach it
+type XDSConfig struct {
+	BindAddress string `json:"bindAddress"`
+	// Host is the address builtIn envoys are bootstrapped with, usually the controller's service
+	Host string `json:"host"`
+}
+
+// WebhookConfig is the admission webhook server, which only runs when CertDir holds tls.crt and tls.key.
+// Sidecars are only injected once SidecarInitImage names an iptables image pinned by digest.
+type WebhookConfig struct {
+	BindAddress      string `json:"bindAddress"`
+	CertDir          string `json:"certDir"`
+	SidecarInitImage string `json:"sidecarInitImage"`
+}
+
+// MetricsConfig is the prometheus metrics server, which does not run when BindAddress is empty
+type MetricsConfig struct {
+	BindAddress string `json:"bindAddress"`
+}
+
+// HealthConfig is the server of the liveness and readiness probes, which does not run when BindAddress is empty
+type HealthConfig struct {
+	BindAddress string `json:"bindAddress"`
+	// WorkerTimeout is how long a worker may spend on one envoy before liveness fails
+	WorkerTimeout metav1.Duration `json:"workerTimeout"`
+}
+
+// LeaderElectionConfig is the Lease replicas compete for
+type LeaderElectionConfig struct {
+	Enabled       bool            `json:"enabled"`
+	Name          string          `json:"name"`
+	Namespace     string          `json:"namespace"`
+	LeaseDuration metav1.Duration `json:"leaseDuration"`
+	RenewDeadline metav1.Duration `json:"renewDeadline"`
+	RetryPeriod   metav1.Duration `json:"retryPeriod"`
+}
+
+// MigrationConfig copies the envoys, routes and listeners of the API group the controller used to serve
+// into the current one, and hands their deployments, services and config maps over to the copies
+type MigrationConfig struct {
+	Enabled bool `json:"enabled"`
+	// Group is the legacy API group, its objects are read as v1
+	Group string `json:"group"`
+}
+
+// Default returns the settings the controller runs with when nothing is configured
+func Default() Config {
+	return Config{
+		Resync:          metav1.Duration{Duration: 30 * time.Second},
+		Workers:         2,
+		MaxRetries:      10,
+		RetryBaseDelay:  metav1.Duration{Duration: 5 * time.Second},
+		RetryMaxDelay:   metav1.Duration{Duration: time.Minute},
+		ShutdownTimeout: metav1.Duration{Duration: 25 * time.Second},
+		Log: LogConfig{
+			Format: logging.FormatText,
+			Level:  "info",
+		},
+		Envoy: EnvoyConfig{
+			Image:     "envoyproxy/envoy:v1.32.1",
+			AdminPort: 15000,
+		},
+		XDS: XDSConfig{
+			BindAddress: ":18000",
+			Host:        "kube-envoy-controller.default",
+		},
+		Webhook: WebhookConfig{
+			BindAddress: ":8443",
+			CertDir:     "/etc/kube-envoy-controller/certs",
+		},
+		Metrics: MetricsConfig{
+			BindAddress: ":8080",
+		},
+		Health: HealthConfig{
+			BindAddress:   ":8081",
+			WorkerTimeout: metav1.Duration{Duration: 5 * time.Minute},
+		},
+		LeaderElection: LeaderElectionConfig{
+			Enabled:       true,
+			Name:          "kube-envoy-controller",
+			Namespace:     "default",
+			LeaseDuration: metav1.Duration{Duration: 15 * time.Second},
+			RenewDeadline: metav1.Duration{Duration: 10 * time.Second},
+			RetryPeriod:   metav1.Duration{Duration: 2 * time.Second},
+		},
+		Migration: MigrationConfig{
+			Group: "example.com",
+		},
+	}
+}
+
+// Load builds the config from args (without the program name), the file they point to and the
+// environment. printConfig reports whether --print-config was given.
+func Load(args []string) (cfg Config, printConfig bool, err error) {
+	flags := flag.NewFlagSet("kube-envoy-controller", flag.ContinueOnError)
+	file := flags.String("config", "", "path of a YAML or JSON controller config file")
+	flags.BoolVar(&printConfig, "print-config", false, "print the resulting config as YAML and exit")
+
+	// flags are bound to a scratch config and only applied when set, so they win over file and environment
+	scratch := Default()
+	namespaces := flags.String("namespaces", "", "comma separated namespaces to watch, all when empty")
+	flags.StringVar(&scratch.LabelSelector, "selector", "", "label selector of the envoys, routes and listeners to manage")
+	flags.StringVar(&scratch.Kubeconfig, "kubeconfig", "", "path to a kubeconfig, in-cluster config when empty")
+	flags.StringVar(&scratch.Master, "master", "", "address of the API server, overrides the kubeconfig")
+	flags.DurationVar(&scratch.Resync.Duration, "resync", scratch.Resync.Duration, "informer resync period")
+	flags.IntVar(&scratch.Workers, "workers", scratch.Workers, "number of envoys reconciled in parallel")
+	flags.IntVar(&scratch.MaxRetries, "max-retries", scratch.MaxRetries, "retries of a failing envoy before giving up")
+	flags.DurationVar(&scratch.RetryBaseDelay.Duration, "retry-base-delay", scratch.RetryBaseDelay.Duration, "first retry delay, doubled on every retry")
+	flags.DurationVar(&scratch.RetryMaxDelay.Duration, "retry-max-delay", scratch.RetryMaxDelay.Duration, "longest retry delay")
+	flags.DurationVar(&scratch.ShutdownTimeout.Duration, "shutdown-timeout", scratch.ShutdownTimeout.Duration, "how long shutdown waits for reconciles and servers")
+	flags.StringVar(&scratch.Log.Format, "log-format", scratch.Log.Format, "log format, text or json")
+	flags.StringVar(&scratch.Log.Level, "log-level", scratch.Log.Level, "lowest level logged, debug, info, warn or error")
+	flags.StringVar(&scratch.Envoy.Image, "envoy-image", scratch.Envoy.Image, "envoy image of generated deployments and sidecars")
+	flags.IntVar(&scratch.Envoy.AdminPort, "envoy-admin-port", scratch.Envoy.AdminPort, "port of the envoy admin interface")
+	flags.StringVar(&scratch.XDS.BindAddress, "xds-bind-address", scratch.XDS.BindAddress, "address the xds server listens on")
+	flags.StringVar(&scratch.XDS.Host, "xds-host", scratch.XDS.Host, "host builtIn envoys connect to for xds")
+	flags.StringVar(&scratch.Webhook.BindAddress, "webhook-bind-address", scratch.Webhook.BindAddress, "address the webhook server listens on")
+	flags.StringVar(&scratch.Webhook.CertDir, "webhook-cert-dir", scratch.Webhook.CertDir, "directory holding the webhook tls.crt and tls.key")
+	flags.StringVar(&scratch.Webhook.SidecarInitImage, "sidecar-init-image", scratch.Webhook.SidecarInitImage, "iptables image of the sidecar init container")
+	flags.StringVar(&scratch.Metrics.BindAddress, "metrics-bind-address", scratch.Metrics.BindAddress, "address the metrics server listens on, empty to disable it")
+	flags.StringVar(&scratch.Health.BindAddress, "health-bind-address", scratch.Health.BindAddress, "address /healthz and /readyz are served on, empty to disable them")
+	flags.DurationVar(&scratch.Health.WorkerTimeout.Duration, "worker-timeout", scratch.Health.WorkerTimeout.Duration, "how long a worker may sync one envoy before liveness fails")
+	flags.BoolVar(&scratch.LeaderElection.Enabled, "leader-elect", scratch.LeaderElection.Enabled, "elect a leader among replicas through a Lease")
+	flags.StringVar(&scratch.LeaderElection.Name, "lease-name", scratch.LeaderElection.Name, "name of the leader election Lease")
+	flags.StringVar(&scratch.LeaderElection.Namespace, "lease-namespace", scratch.LeaderElection.Namespace, "namespace of the leader election Lease")
+	flags.DurationVar(&scratch.LeaderElection.LeaseDuration.Duration, "lease-duration", scratch.LeaderElection.LeaseDuration.Duration, "how long a lease is valid without renewal")
+	flags.DurationVar(&scratch.LeaderElection.RenewDeadline.Duration, "lease-renew-deadline", scratch.LeaderElection.RenewDeadline.Duration, "how long the leader retries renewing before giving up")
+	flags.DurationVar(&scratch.LeaderElection.RetryPeriod.Duration, "lease-retry-period", scratch.LeaderElection.RetryPeriod.Duration, "interval between lease attempts")
+	flags.BoolVar(&scratch.Migration.Enabled, "migrate", scratch.Migration.Enabled, "copy envoys, routes and listeners of the legacy API group and take over their fleets")
+	flags.StringVar(&scratch.Migration.Group, "migrate-group", scratch.Migration.Group, "legacy API group to migrate from")
+	if err := flags.Parse(args); err != nil {
+		return cfg, false, err
+	}
+	if *namespaces != "" {
+		scratch.Namespaces = splitList(*namespaces)
+	}
+
+	cfg = Default()
+	if *file != "" {
+		data, err := ioutil.ReadFile(*file)
+		if err != nil {
+			return cfg, false, err
+		}
+		if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+			return cfg, false, fmt.Errorf("%s: %v", *file, err)
+		}
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return cfg, false, err
+	}
+	flags.Visit(func(f *flag.Flag) {
+		applyFlag(&cfg, &scratch, f.Name)
+	})
+	return cfg, printConfig, cfg.Validate()
+}
+
+// applyEnv applies the environment variables the controller has always read
+func applyEnv(cfg *Config) error {
+	strs := []struct {
+		env   string
+		value *string
+	}{
+		{"KUBECONFIG", &cfg.Kubeconfig},
+		{"XDS_HOST", &cfg.XDS.Host},
+		{"WEBHOOK_CERT_DIR", &cfg.Webhook.CertDir},
+		{"SIDECAR_INIT_IMAGE", &cfg.Webhook.SidecarInitImage},
+		{"LOG_FORMAT", &cfg.Log.Format},
+		{"LOG_LEVEL", &cfg.Log.Level},
+		{"LEASE_NAME", &cfg.LeaderElection.Name},
+		{"POD_NAMESPACE", &cfg.LeaderElection.Namespace},
+	}
+	for _, s := range strs {
+		if value := os.Getenv(s.env); value != "" {
+			*s.value = value
+		}
+	}
+	// LEASE_NAMESPACE wins over the pod's own namespace
+	if value := os.Getenv("LEASE_NAMESPACE"); value != "" {
+		cfg.LeaderElection.Namespace = value
+	}
+	if value := os.Getenv("WATCH_NAMESPACES"); value != "" {
+		cfg.Namespaces = splitList(value)
+	}
+	if value := os.Getenv("WATCH_SELECTOR"); value != "" {
+		cfg.LabelSelector = value
+	}
+	if value := os.Getenv("WORKERS"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("WORKERS: %v", err)
+		}
+		cfg.Workers = n
+	}
+	if value := os.Getenv("LEADER_ELECT"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("LEADER_ELECT: %v", err)
+		}
+		cfg.LeaderElection.Enabled = enabled
+	}
+	durations := []struct {
+		env   string
+		value *time.Duration
+	}{
+		{"LEASE_DURATION", &cfg.LeaderElection.LeaseDuration.Duration},
+		{"LEASE_RENEW_DEADLINE", &cfg.LeaderElection.RenewDeadline.Duration},
+		{"LEASE_RETRY_PERIOD", &cfg.LeaderElection.RetryPeriod.Duration},
+	}
+	for _, d := range durations {
+		value := os.Getenv(d.env)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", d.env, err)
+		}
+		*d.value = parsed
+	}
+	return nil
+}
+
+func applyFlag(cfg, flagged *Config, name string) {
+	switch name {
+	case "namespaces":
+		cfg.Namespaces = flagged.Namespaces
+	case "selector":
+		cfg.LabelSelector = flagged.LabelSelector
+	case "kubeconfig":
+		cfg.Kubeconfig = flagged.Kubeconfig
+	case "master":
+		cfg.Master = flagged.Master
+	case "resync":
+		cfg.Resync = flagged.Resync
+	case "workers":
+		cfg.Workers = flagged.Workers
+	case "max-retries":
+		cfg.MaxRetries = flagged.MaxRetries
+	case "retry-base-delay":
+		cfg.RetryBaseDelay = flagged.RetryBaseDelay
+	case "retry-max-delay":
+		cfg.RetryMaxDelay = flagged.RetryMaxDelay
+	case "shutdown-timeout":
+		cfg.ShutdownTimeout = flagged.ShutdownTimeout
+	case "log-format":
+		cfg.Log.Format = flagged.Log.Format
+	case "log-level":
+		cfg.Log.Level = flagged.Log.Level
+	case "envoy-image":
+		cfg.Envoy.Image = flagged.Envoy.Image
+	case "envoy-admin-port":
+		cfg.Envoy.AdminPort = flagged.Envoy.AdminPort
+	case "xds-bind-address":
+		cfg.XDS.BindAddress = flagged.XDS.BindAddress
+	case "xds-host":
+		cfg.XDS.Host = flagged.XDS.Host
+	case "webhook-bind-address":
+		cfg.Webhook.BindAddress = flagged.Webhook.BindAddress
+	case "webhook-cert-dir":
+		cfg.Webhook.CertDir = flagged.Webhook.CertDir
+	case "sidecar-init-image":
+		cfg.Webhook.SidecarInitImage = flagged.Webhook.SidecarInitImage
+	case "metrics-bind-address":
+		cfg.Metrics.BindAddress = flagged.Metrics.BindAddress
+	case "health-bind-address":
+		cfg.Health.BindAddress = flagged.Health.BindAddress
+	case "worker-timeout":
+		cfg.Health.WorkerTimeout = flagged.Health.WorkerTimeout
+	case "leader-elect":
+		cfg.LeaderElection.Enabled = flagged.LeaderElection.Enabled
+	case "lease-name":
+		cfg.LeaderElection.Name = flagged.LeaderElection.Name
+	case "lease-namespace":
+		cfg.LeaderElection.Namespace = flagged.LeaderElection.Namespace
+	case "lease-duration":
+		cfg.LeaderElection.LeaseDuration = flagged.LeaderElection.LeaseDuration
+	case "lease-renew-deadline":
+		cfg.LeaderElection.RenewDeadline = flagged.LeaderElection.RenewDeadline
+	case "lease-retry-period":
+		cfg.LeaderElection.RetryPeriod = flagged.LeaderElection.RetryPeriod
+	case "migrate":
+		cfg.Migration.Enabled = flagged.Migration.Enabled
+	case "migrate-group":
+		cfg.Migration.Group = flagged.Migration.Group
+	}
+}
+
+// digestPinned matches an image reference ending in a sha256 digest
+var digestPinned = regexp.MustCompile(`^[^@\s]+@sha256:[0-9a-f]{64}$`)
+
+// Validate reports the first setting the controller cannot run with. Settings are checked in a
+// fixed order, so the same config always reports the same setting.
+func (c Config) Validate() error {
+	positive := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"resync", c.Resync.Duration},
+		{"retryBaseDelay", c.RetryBaseDelay.Duration},
+		{"retryMaxDelay", c.RetryMaxDelay.Duration},
+		{"shutdownTimeout", c.ShutdownTimeout.Duration},
+		{"health.workerTimeout", c.Health.WorkerTimeout.Duration},
+		{"leaderElection.leaseDuration", c.LeaderElection.LeaseDuration.Duration},
+		{"leaderElection.renewDeadline", c.LeaderElection.RenewDeadline.Duration},
+		{"leaderElection.retryPeriod", c.LeaderElection.RetryPeriod.Duration},
+	}
+	for _, d := range positive {
+		if d.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %v", d.name, d.value)
+		}
+	}
+	if c.RetryMaxDelay.Duration < c.RetryBaseDelay.Duration {
+		return fmt.Errorf("retryMaxDelay %v is shorter than retryBaseDelay %v", c.RetryMaxDelay.Duration, c.RetryBaseDelay.Duration)
+	}
+	if c.Workers < 1 {
+		return fmt.Errorf("workers must be at least 1, got %d", c.Workers)
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("maxRetries must not be negative, got %d", c.MaxRetries)
+	}
+	if c.Log.Format != logging.FormatText && c.Log.Format != logging.FormatJSON {
+		return fmt.Errorf("log.format must be %s or %s, got %q", logging.FormatText, logging.FormatJSON, c.Log.Format)
+	}
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		return fmt.Errorf("log.level: %v", err)
+	}
+	if c.Envoy.Image == "" {
+		return fmt.Errorf("envoy.image must be set")
+	}
+	if c.Envoy.AdminPort < 1 || c.Envoy.AdminPort > 65535 {
+		return fmt.Errorf("envoy.adminPort %d is not a port", c.Envoy.AdminPort)
+	}
+	if image := c.Webhook.SidecarInitImage; image != "" && !digestPinned.MatchString(image) {
+		return fmt.Errorf("webhook.sidecarInitImage %q must be pinned by digest, as in name@sha256:<digest>", image)
+	}
+	if c.XDS.Host == "" {
+		return fmt.Errorf("xds.host must be set")
+	}
+	addresses := []struct {
+		name  string
+		value string
+		// optional servers do not run when their address is empty
+		optional bool
+	}{
+		{"xds.bindAddress", c.XDS.BindAddress, false},
+		{"webhook.bindAddress", c.Webhook.BindAddress, false},
+		{"metrics.bindAddress", c.Metrics.BindAddress, true},
+		{"health.bindAddress", c.Health.BindAddress, true},
+	}
+	for _, addr := range addresses {
+		if addr.optional && addr.value == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr.value); err != nil {
+			return fmt.Errorf("%s: %v", addr.name, err)
+		}
+	}
+	if c.LeaderElection.Enabled {
+		le := c.LeaderElection
+		if le.Name == "" || le.Namespace == "" {
+			return fmt.Errorf("leaderElection.name and leaderElection.namespace must be set")
+		}
+		if le.LeaseDuration.Duration <= le.RenewDeadline.Duration || le.RenewDeadline.Duration <= le.RetryPeriod.Duration {
+			return fmt.Errorf("leaderElection needs leaseDuration > renewDeadline > retryPeriod")
+		}
+	}
+	if c.Migration.Enabled {
+		if errs := validation.IsDNS1123Subdomain(c.Migration.Group); len(errs) > 0 {
+			return fmt.Errorf("migration.group %q: %s", c.Migration.Group, strings.Join(errs, ", "))
+		}
+		if c.Migration.Group == v1.SchemeGroupVersion.Group {
+			return fmt.Errorf("migration.group %q is the group objects are migrated to", c.Migration.Group)
+		}
+	}
+	seen := map[string]bool{}
+	for _, namespace := range c.Namespaces {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fmt.Errorf("namespace %q: %s", namespace, strings.Join(errs, ", "))
+		}
+		if seen[namespace] {
+			return fmt.Errorf("namespace %q is listed twice", namespace)
+		}
+		seen[namespace] = true
+	}
+	if _, err := labels.Parse(c.LabelSelector); err != nil {
+		return fmt.Errorf("labelSelector: %v", err)
+	}
+	return nil
+}
+
+// YAML renders the config the way --config reads it
+func (c Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+func splitList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// environment lists every variable Load reads
+var environment = []string{
+	"KUBECONFIG", "XDS_HOST", "WEBHOOK_CERT_DIR", "SIDECAR_INIT_IMAGE", "LOG_FORMAT", "LOG_LEVEL",
+	"LEASE_NAME", "POD_NAMESPACE", "LEASE_NAMESPACE", "WATCH_NAMESPACES", "WATCH_SELECTOR", "WORKERS",
+	"LEADER_ELECT", "LEASE_DURATION", "LEASE_RENEW_DEADLINE", "LEASE_RETRY_PERIOD",
+}
+
+// clearEnv unsets the environment Load reads for the duration of the test
+func clearEnv(t *testing.T) {
+	for _, env := range environment {
+		t.Setenv(env, "")
+	}
+}
+
+func writeFile(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := `
+workers: 3
+namespaces: [file]
+xds:
+  host: xds.file
+leaderElection:
+  namespace: file
+`
+	tests := []struct {
+		name string
+		file bool
+		env  map[string]string
+		args []string
+		// want edits the defaults into the expected config
+		want func(c *Config)
+	}{
+		{name: "defaults", want: func(c *Config) {}},
+		{name: "file over defaults", file: true, want: func(c *Config) {
+			c.Workers, c.Namespaces, c.XDS.Host, c.LeaderElection.Namespace = 3, []string{"file"}, "xds.file", "file"
+		}},
+		{name: "environment over file", file: true,
+			env: map[string]string{"WORKERS": "4", "WATCH_NAMESPACES": "a, b", "POD_NAMESPACE": "pod"},
+			want: func(c *Config) {
+				c.Workers, c.Namespaces, c.XDS.Host, c.LeaderElection.Namespace = 4, []string{"a", "b"}, "xds.file", "pod"
+			}},
+		{name: "lease namespace over pod namespace",
+			env:  map[string]string{"POD_NAMESPACE": "pod", "LEASE_NAMESPACE": "lease"},
+			want: func(c *Config) { c.LeaderElection.Namespace = "lease" }},
+		{name: "flags over environment and file", file: true,
+			env:  map[string]string{"WORKERS": "4", "XDS_HOST": "xds.env", "LEASE_DURATION": "30s"},
+			args: []string{"--workers=5", "--namespaces=flag", "--lease-duration=20s"},
+			want: func(c *Config) {
+				c.Workers, c.Namespaces, c.XDS.Host, c.LeaderElection.Namespace = 5, []string{"flag"}, "xds.env", "file"
+				c.LeaderElection.LeaseDuration.Duration = 20 * time.Second
+			}},
+		{name: "flags set to their default still win", file: true, args: []string{"--workers=2"},
+			want: func(c *Config) {
+				c.Namespaces, c.XDS.Host, c.LeaderElection.Namespace = []string{"file"}, "xds.file", "file"
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for env, value := range tt.env {
+				t.Setenv(env, value)
+			}
+			args := tt.args
+			if tt.file {
+				args = append([]string{"--config", writeFile(t, file)}, args...)
+			}
+			got, printConfig, err := Load(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if printConfig {
+				t.Error("print-config set without the flag")
+			}
+			want := Default()
+			tt.want(&want)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		// err is a substring of the error
+		err string
+	}{
+		{name: "unknown flag", args: []string{"--worker=3"}, err: "flag provided but not defined"},
+		{name: "missing file", args: []string{"--config", "/nonexistent/config.yaml"}, err: "no such file"},
+		{name: "unknown file field", file: "wrokers: 3\n", err: `unknown field "wrokers"`},
+		{name: "bad environment number", env: map[string]string{"WORKERS": "two"}, err: "WORKERS"},
+		{name: "bad environment duration", env: map[string]string{"LEASE_RETRY_PERIOD": "often"}, err: "LEASE_RETRY_PERIOD"},
+		{name: "invalid result", args: []string{"--workers=0"}, err: "workers must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for env, value := range tt.env {
+				t.Setenv(env, value)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"--config", writeFile(t, tt.file)}, args...)
+			}
+			_, _, err := Load(args)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want one mentioning %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLoadPrintConfig(t *testing.T) {
+	clearEnv(t)
+	cfg, printConfig, err := Load([]string{"--print-config", "--selector", "team=edge"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !printConfig {
+		t.Fatal("print-config not reported")
+	}
+	// the printed config reads back as a config file
+	out, err := cfg.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, _, err := Load([]string{"--config", writeFile(t, string(out))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reloaded, cfg) {
+		t.Fatalf("printed config reads back as %+v, want %+v", reloaded, cfg)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(c *Config)
+		// err is a substring of the error, empty when the config is valid
+		err string
+	}{
+		{name: "defaults", edit: func(c *Config) {}},
+		{name: "zero resync", edit: func(c *Config) { c.Resync.Duration = 0 }, err: "resync must be positive"},
+		{name: "first of several bad durations", err: "resync must be positive",
+			edit: func(c *Config) {
+				c.Resync.Duration, c.Health.WorkerTimeout.Duration, c.LeaderElection.RetryPeriod.Duration = 0, 0, 0
+			}},
+		{name: "max delay under base delay", edit: func(c *Config) { c.RetryMaxDelay.Duration = time.Second }, err: "retryMaxDelay 1s is shorter"},
+		{name: "no workers", edit: func(c *Config) { c.Workers = 0 }, err: "workers must be at least 1"},
+		{name: "negative retries", edit: func(c *Config) { c.MaxRetries = -1 }, err: "maxRetries"},
+		{name: "log format", edit: func(c *Config) { c.Log.Format = "xml" }, err: "log.format"},
+		{name: "log level", edit: func(c *Config) { c.Log.Level = "trace" }, err: "log.level"},
+		{name: "no envoy image", edit: func(c *Config) { c.Envoy.Image = "" }, err: "envoy.image"},
+		{name: "admin port", edit: func(c *Config) { c.Envoy.AdminPort = 70000 }, err: "envoy.adminPort"},
+		{name: "init image by tag", edit: func(c *Config) { c.Webhook.SidecarInitImage = "iptables:latest" }, err: "pinned by digest"},
+		{name: "init image by digest",
+			edit: func(c *Config) { c.Webhook.SidecarInitImage = "iptables@sha256:" + strings.Repeat("ab", 32) }},
+		{name: "no xds host", edit: func(c *Config) { c.XDS.Host = "" }, err: "xds.host"},
+		{name: "first of several bad addresses", err: "xds.bindAddress",
+			edit: func(c *Config) {
+				c.XDS.BindAddress, c.Webhook.BindAddress, c.Health.BindAddress = "xds", "webhook", "health"
+			}},
+		{name: "bad metrics address", edit: func(c *Config) { c.Metrics.BindAddress = "8080" }, err: "metrics.bindAddress"},
+		{name: "metrics and health off", edit: func(c *Config) { c.Metrics.BindAddress, c.Health.BindAddress = "", "" }},
+		{name: "lease without namespace", edit: func(c *Config) { c.LeaderElection.Namespace = "" }, err: "leaderElection.name"},
+		{name: "lease durations out of order", edit: func(c *Config) { c.LeaderElection.RenewDeadline.Duration = time.Minute }, err: "leaseDuration > renewDeadline"},
+		{name: "lease unchecked without election", edit: func(c *Config) {
+			c.LeaderElection.Enabled, c.LeaderElection.Namespace = false, ""
+		}},
+		{name: "migration group", edit: func(c *Config) { c.Migration.Enabled, c.Migration.Group = true, "Example_com" }, err: "migration.group"},
+		{name: "migration from the current group", err: "is the group objects are migrated to",
+			edit: func(c *Config) { c.Migration.Enabled, c.Migration.Group = true, "envoy.starizard.io" }},
+		{name: "migration group unchecked when off", edit: func(c *Config) { c.Migration.Group = "envoy.starizard.io" }},
+		{name: "namespace", edit: func(c *Config) { c.Namespaces = []string{"Shop"} }, err: `namespace "Shop"`},
+		{name: "namespace twice", edit: func(c *Config) { c.Namespaces = []string{"shop", "shop"} }, err: "listed twice"},
+		{name: "label selector", edit: func(c *Config) { c.LabelSelector = "team in (" }, err: "labelSelector"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.edit(&cfg)
+			// the same config reports the same setting every time
+			for i := 0; i < 20; i++ {
+				err := cfg.Validate()
+				if tt.err == "" {
+					if err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want one mentioning %q", err, tt.err)
+				}
+			}
+		})
+	}
+}
@@ -14,18 +14,22 @@ type GrpcServices struct {
 	Grpc Grpc `json:"envoy_grpc"`
 }
 type AdsConfig struct {
-	APIType      string       `json:"api_type"`
-	GrpcServices GrpcServices `json:"grpc_services"`
+	APIType             string       `json:"api_type"`
+	TransportAPIVersion string       `json:"transport_api_version"`
+	GrpcServices        GrpcServices `json:"grpc_services"`
 }
 type APIConfigSource struct {
-	APIType      string       `json:"api_type"`
-	GrpcServices GrpcServices `json:"grpc_services"`
+	APIType             string       `json:"api_type"`
+	TransportAPIVersion string       `json:"transport_api_version"`
+	GrpcServices        GrpcServices `json:"grpc_services"`
 }
 type CdsConfig struct {
-	APIConfigSource APIConfigSource `json:"api_config_source"`
+	APIConfigSource    APIConfigSource `json:"api_config_source"`
+	ResourceAPIVersion string          `json:"resource_api_version"`
 }
 type LdsConfig struct {
-	APIConfigSource APIConfigSource `json:"api_config_source"`
+	APIConfigSource    APIConfigSource `json:"api_config_source"`
+	ResourceAPIVersion string          `json:"resource_api_version"`
 }
 type DynamicResources struct {
 	AdsConfig AdsConfig `json:"ads_config"`
@@ -33,15 +37,26 @@ type DynamicResources struct {
 	LdsConfig LdsConfig `json:"lds_config"`
 }
 type Node struct {
-	Cluster string `json:"cluster"`
-	ID      string `json:"id"`
+	Cluster  string            `json:"cluster"`
+	ID       string            `json:"id"`
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 type SocketAddress struct {
 	Address   string `json:"address"`
 	PortValue int    `json:"port_value"`
 }
-type Hosts struct {
-	SocketAddress SocketAddress `json:"socket_address"`
+type Endpoint struct {
+	Address Address `json:"address"`
+}
+type LbEndpoints struct {
+	Endpoint Endpoint `json:"endpoint"`
+}
+type LocalityLbEndpoints struct {
+	LbEndpoints []LbEndpoints `json:"lb_endpoints"`
+}
+type LoadAssignment struct {
+	ClusterName string                `json:"cluster_name"`
+	Endpoints   []LocalityLbEndpoints `json:"endpoints"`
 }
 type HTTP2ProtocolOptions struct {
 }
@@ -49,7 +64,7 @@ type Clusters struct {
 	Name                 string               `json:"name"`
 	Type                 string               `json:"type"`
 	ConnectTimeout       string               `json:"connect_timeout"`
-	Hosts                []Hosts              `json:"hosts"`
+	LoadAssignment       LoadAssignment       `json:"load_assignment"`
 	HTTP2ProtocolOptions HTTP2ProtocolOptions `json:"http2_protocol_options"`
 }
 type StaticResources struct {
@@ -59,7 +74,6 @@ type Address struct {
 	SocketAddress SocketAddress `json:"socket_address"`
 }
 type Admin struct {
-	AccessLogPath string  `json:"access_log_path"`
-	ProfilePath   string  `json:"profile_path"`
-	Address       Address `json:"address"`
+	ProfilePath string  `json:"profile_path,omitempty"`
+	Address     Address `json:"address"`
 }
@@ -0,0 +1,108 @@
+package envoy
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func bootstrapEnvoy(spec v1.EnvoySpec) *v1.Envoy {
+	envoy := &v1.Envoy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "edge", UID: "edge-uid"},
+		Spec:       spec,
+	}
+	Default(envoy)
+	return envoy
+}
+
+func TestBootstrapGolden(t *testing.T) {
+	tests := []struct {
+		golden string
+		cfgMap func() (*apiv1.ConfigMap, error)
+	}{
+		{"builtin.golden", func() (*apiv1.ConfigMap, error) {
+			return ConfigMap(bootstrapEnvoy(v1.EnvoySpec{}))
+		}},
+		{"external-xds.golden", func() (*apiv1.ConfigMap, error) {
+			return ConfigMap(bootstrapEnvoy(v1.EnvoySpec{XDS: v1.EnvoyXDS{Name: "mesh", Host: "xds.mesh", Port: 15010}}))
+		}},
+		{"admin-port.golden", func() (*apiv1.ConfigMap, error) {
+			return ConfigMap(bootstrapEnvoy(v1.EnvoySpec{AdminPort: 9901}))
+		}},
+		{"overrides.golden", func() (*apiv1.ConfigMap, error) {
+			overrides := &runtime.RawExtension{Raw: []byte(`{"admin":{"profile_path":"/tmp/envoy.prof"},"layered_runtime":{"layers":[{"name":"static","static_layer":{"overload.global_downstream_max_connections":50000}}]}}`)}
+			return ConfigMap(bootstrapEnvoy(v1.EnvoySpec{BootstrapOverrides: overrides}))
+		}},
+		{"sidecar.golden", func() (*apiv1.ConfigMap, error) {
+			return SidecarConfigMap(bootstrapEnvoy(v1.EnvoySpec{Name: "envoy-sidecar", ConfigMapName: "envoy-sidecar"}))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			cfgMap, err := tt.cfgMap()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := cfgMap.Data["envoy.yaml"]
+			path := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := ioutil.WriteFile(path, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v, run go test -update to create it", err)
+			}
+			if got != string(want) {
+				t.Errorf("bootstrap differs from %s, run go test -update if the change is intended\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+func hash(t *testing.T, envoy *v1.Envoy) string {
+	t.Helper()
+	h, err := BootstrapHash(envoy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestBootstrapHashFollowsBootstrap(t *testing.T) {
+	builtIn := bootstrapEnvoy(v1.EnvoySpec{})
+	if hash(t, builtIn) != hash(t, bootstrapEnvoy(v1.EnvoySpec{})) {
+		t.Fatal("the same spec hashes differently")
+	}
+	if hash(t, builtIn) == hash(t, bootstrapEnvoy(v1.EnvoySpec{AdminPort: 9901})) {
+		t.Fatal("a different bootstrap hashes the same")
+	}
+}
+
+func TestBadBootstrapOverrides(t *testing.T) {
+	for _, raw := range []string{`{"admin":`, `"admin"`, `null`} {
+		t.Run(raw, func(t *testing.T) {
+			envoy := bootstrapEnvoy(v1.EnvoySpec{BootstrapOverrides: &runtime.RawExtension{Raw: []byte(raw)}})
+			if _, err := ConfigMap(envoy); err == nil || !strings.Contains(err.Error(), "bootstrapOverrides") {
+				t.Errorf("ConfigMap error %v, want one about bootstrapOverrides", err)
+			}
+			if _, err := BootstrapHash(envoy); err == nil {
+				t.Error("BootstrapHash rendered the bootstrap")
+			}
+			if _, err := Deployment(envoy, nil); err == nil {
+				t.Error("Deployment rendered the bootstrap")
+			}
+		})
+	}
+}
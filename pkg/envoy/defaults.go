@@ -0,0 +1,46 @@
+package envoy
+
+import (
+	"reflect"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+)
+
+// DefaultReplicas is the size of a fleet whose spec does not set replicas
+const DefaultReplicas int32 = 1
+
+// Default fills in what the spec of envoy leaves empty and reports whether it changed anything. The
+// defaulting webhook and the controller share it, so envoys created before the webhook was installed,
+// or while it was down, end up with the same spec. Image and AdminPort are taken from the controller
+// config at the time, later changes to it do not roll fleets that were defaulted before.
+func Default(envoy *v1.Envoy) bool {
+	spec := &envoy.Spec
+	before := spec.DeepCopy()
+
+	if spec.Name == "" {
+		spec.Name = envoy.Name
+	}
+	if spec.ConfigMapName == "" {
+		spec.ConfigMapName = envoy.Name
+	}
+	if spec.Replicas == nil {
+		replicas := DefaultReplicas
+		spec.Replicas = &replicas
+	}
+	if !spec.XDS.BuiltIn && spec.XDS.Host == "" && spec.XDS.Port == 0 {
+		spec.XDS.BuiltIn = true
+	}
+	if !spec.XDS.BuiltIn && spec.XDS.Name == "" {
+		spec.XDS.Name = BuiltInXDS.Name
+	}
+	if spec.DeletionPolicy == "" {
+		spec.DeletionPolicy = v1.DeletionPolicyDelete
+	}
+	if spec.Image == "" {
+		spec.Image = Image
+	}
+	if spec.AdminPort == 0 {
+		spec.AdminPort = int32(AdminPort)
+	}
+	return !reflect.DeepEqual(before, spec)
+}
@@ -0,0 +1,172 @@
+package envoy
+
+import (
+	"fmt"
+	"reflect"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	client "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned"
+)
+
+// Observed is what a sync of an envoy found out about its generated objects
+type Observed struct {
+	// Deployment and Service are nil when they could not be synced
+	Deployment *appsv1.Deployment
+	Service    *apiv1.Service
+	// ConfigErr and SyncErr are the errors syncing the config map and everything else
+	ConfigErr error
+	SyncErr   error
+	// XDSConnected counts the proxies connected to the builtIn xds server
+	XDSConnected int
+}
+
+// Status computes the status of envoy from what its last sync observed
+func Status(envoy *v1.Envoy, observed Observed) v1.EnvoyStatus {
+	status := v1.EnvoyStatus{
+		ObservedGeneration: envoy.Generation,
+		Conditions:         envoy.Status.Conditions,
+		DeploymentName:     envoy.Spec.Name,
+		ServiceName:        envoy.Spec.Name,
+		ConfigMapName:      envoy.Spec.ConfigMapName,
+		BootstrapHash:      envoy.Status.BootstrapHash,
+		Rollout:            v1.RolloutProgressing,
+	}
+	if d := observed.Deployment; d != nil {
+		status.AvailableReplicas = d.Status.AvailableReplicas
+		status.Replicas = d.Status.Replicas
+		status.UpdatedReplicas = d.Status.UpdatedReplicas
+		status.Rollout = rolloutState(d)
+	}
+	if observed.Service != nil {
+		status.ServiceAddress = observed.Service.Spec.ClusterIP
+	}
+
+	// a bootstrap that cannot be rendered leaves the last one in the config map
+	if hash, err := BootstrapHash(envoy); err == nil {
+		status.BootstrapHash = hash
+	}
+	if observed.ConfigErr != nil {
+		status.Conditions = SetCondition(status.Conditions, v1.EnvoyConfigRendered, apiv1.ConditionFalse, "SyncFailed", observed.ConfigErr.Error())
+	} else {
+		status.Conditions = SetCondition(status.Conditions, v1.EnvoyConfigRendered, apiv1.ConditionTrue, "Rendered", "bootstrap "+status.BootstrapHash)
+	}
+
+	if status.Rollout == v1.RolloutProgressing {
+		status.Conditions = SetCondition(status.Conditions, v1.EnvoyProgressing, apiv1.ConditionTrue, "RollingOut",
+			fmt.Sprintf("%d of %d replicas updated", status.UpdatedReplicas, desiredReplicas(observed.Deployment)))
+	} else {
+		status.Conditions = SetCondition(status.Conditions, v1.EnvoyProgressing, apiv1.ConditionFalse, "Complete", "")
+	}
+
+	switch {
+	case !envoy.Spec.XDS.BuiltIn:
+		status.Conditions = SetCondition(status.Conditions, v1.EnvoyXDSConnected, apiv1.ConditionUnknown, "ExternalXDS",
+			fmt.Sprintf("proxies connect to %s:%d", envoy.Spec.XDS.Host, envoy.Spec.XDS.Port))
+	case observed.XDSConnected > 0:
+		status.Conditions = SetCondition(status.Conditions, v1.EnvoyXDSConnected, apiv1.ConditionTrue, "Connected",
+			fmt.Sprintf("%d proxies connected", observed.XDSConnected))
+	default:
+		status.Conditions = SetCondition(status.Conditions, v1.EnvoyXDSConnected, apiv1.ConditionFalse, "NoProxies", "no proxy is connected")
+	}
+
+	degraded, reason, message := degradation(observed)
+	if degraded {
+		status.Conditions = SetCondition(status.Conditions, v1.EnvoyDegraded, apiv1.ConditionTrue, reason, message)
+	} else {
+		status.Conditions = SetCondition(status.Conditions, v1.EnvoyDegraded, apiv1.ConditionFalse, "AsExpected", "")
+	}
+
+	ready := !degraded && observed.Service != nil && status.Rollout == v1.RolloutComplete
+	if ready {
+		status.Conditions = SetCondition(status.Conditions, v1.EnvoyReady, apiv1.ConditionTrue, "Ready",
+			fmt.Sprintf("%d replicas available", status.AvailableReplicas))
+	} else {
+		status.Conditions = SetCondition(status.Conditions, v1.EnvoyReady, apiv1.ConditionFalse, "NotReady",
+			fmt.Sprintf("%d of %d replicas available", status.AvailableReplicas, desiredReplicas(observed.Deployment)))
+	}
+	return status
+}
+
+// UpdateStatus writes status through the status subresource unless envoy already has it
+func UpdateStatus(clientset client.Interface, envoy *v1.Envoy, status v1.EnvoyStatus) error {
+	if reflect.DeepEqual(status, envoy.Status) {
+		return nil
+	}
+	updatedObj := envoy.DeepCopy()
+	updatedObj.Status = status
+	_, err := clientset.EnvoyV1().Envoys(envoy.Namespace).UpdateStatus(updatedObj)
+	return err
+}
+
+// degradation reports sync errors first, then a rollout the deployment controller gave up on
+func degradation(observed Observed) (bool, string, string) {
+	if observed.SyncErr != nil {
+		return true, "SyncFailed", observed.SyncErr.Error()
+	}
+	if observed.ConfigErr != nil {
+		return true, "SyncFailed", observed.ConfigErr.Error()
+	}
+	if observed.Deployment != nil {
+		for _, c := range observed.Deployment.Status.Conditions {
+			if c.Type == appsv1.DeploymentProgressing && c.Status == apiv1.ConditionFalse {
+				return true, c.Reason, c.Message
+			}
+			if c.Type == appsv1.DeploymentReplicaFailure && c.Status == apiv1.ConditionTrue {
+				return true, c.Reason, c.Message
+			}
+		}
+	}
+	return false, "", ""
+}
+
+// SetCondition replaces the condition of type t, keeping its transition time while the status holds
+func SetCondition(conditions []v1.EnvoyCondition, t v1.EnvoyConditionType, status apiv1.ConditionStatus, reason, message string) []v1.EnvoyCondition {
+	condition := v1.EnvoyCondition{
+		Type:               t,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+	out := make([]v1.EnvoyCondition, 0, len(conditions)+1)
+	found := false
+	for _, c := range conditions {
+		if c.Type != t {
+			out = append(out, c)
+			continue
+		}
+		if c.Status == status {
+			condition.LastTransitionTime = c.LastTransitionTime
+		}
+		out = append(out, condition)
+		found = true
+	}
+	if !found {
+		out = append(out, condition)
+	}
+	return out
+}
+
+// rolloutState is complete once the deployment controller has seen the latest spec and every pod is updated and available
+func rolloutState(deployment *appsv1.Deployment) v1.RolloutState {
+	replicas := desiredReplicas(deployment)
+	status := deployment.Status
+	if status.ObservedGeneration < deployment.Generation ||
+		status.UpdatedReplicas < replicas ||
+		status.Replicas > status.UpdatedReplicas ||
+		status.AvailableReplicas < status.UpdatedReplicas {
+		return v1.RolloutProgressing
+	}
+	return v1.RolloutComplete
+}
+
+func desiredReplicas(deployment *appsv1.Deployment) int32 {
+	if deployment == nil || deployment.Spec.Replicas == nil {
+		return 1
+	}
+	return *deployment.Spec.Replicas
+}
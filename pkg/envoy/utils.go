@@ -1,148 +1,301 @@
 package envoy
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"log"
+	"fmt"
+	"strings"
 
+	jsonpatch "github.com/evanphx/json-patch"
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
-	v1 "github.com/starizard/kube-envoy-controller/pkg/api/example.com/v1"
-	client "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned"
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	"github.com/starizard/kube-envoy-controller/pkg/xds"
 )
 
+// BootstrapHashAnnotation on the pod template holds the hash of the bootstrap the pods were started with.
+// It keeps the example.com prefix, renaming it would roll every fleet.
+const BootstrapHashAnnotation = "envoy.example.com/bootstrap-hash"
+
+// FleetLabel on the pods of a fleet holds its deployment name, so that fleets sharing a namespace select only their own pods
+const FleetLabel = "envoy.starizard.io/name"
+
 var apiType = "GRPC"
+var apiVersion = "V3"
+
+// Image is the envoy image injected sidecars run and fleets default to
+var Image = "envoyproxy/envoy:v1.32.1"
+
+// AdminPort is where the envoy admin interface listens, on localhost only, unless a fleet sets its own
+var AdminPort = 15000
+
+// BuiltInXDS is how envoys reach the xds server embedded in the controller
+var BuiltInXDS = v1.EnvoyXDS{
+	Name: "xds_cluster",
+	Host: "kube-envoy-controller.default",
+	Port: 18000,
+}
 
-//Deployment returns a spec for an envoy deployment
-func Deployment(envoy *v1.Envoy) *appsv1.Deployment {
+// Deployment returns a spec for an envoy deployment exposing a container port per listener, or why
+// the bootstrap it is annotated with could not be rendered
+func Deployment(envoy *v1.Envoy, listeners []*v1.EnvoyListener) (*appsv1.Deployment, error) {
+	hash, err := BootstrapHash(envoy)
+	if err != nil {
+		return nil, err
+	}
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: envoy.Spec.Name,
+			Name:            envoy.Spec.Name,
+			OwnerReferences: OwnerReferences(envoy),
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: envoy.Spec.Replicas,
 			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app": "envoy",
-				},
+				MatchLabels: Labels(envoy),
 			},
+			Strategy: rolloutStrategy(envoy),
 			Template: apiv1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app": "envoy",
+					Labels: Labels(envoy),
+					// envoy reads its bootstrap once, a new hash rolls the pods onto the new one
+					Annotations: map[string]string{
+						BootstrapHashAnnotation: hash,
 					},
 				},
 				Spec: apiv1.PodSpec{
 					Containers: []apiv1.Container{
 						{
-							Name:    "envoy",
-							Image:   "envoyproxy/envoy:v1.10.0",
-							Command: []string{"envoy"},
-							Args:    []string{"-c", "/etc/envoy.yaml"},
-							VolumeMounts: []apiv1.VolumeMount{
-								apiv1.VolumeMount{
-									Name:      "envoy-yaml",
-									MountPath: "/etc/envoy.yaml",
-									SubPath:   "envoy.yaml",
-								},
-							},
-							Ports: []apiv1.ContainerPort{
-								{
-									Name:          "http",
-									Protocol:      apiv1.ProtocolTCP,
-									ContainerPort: 8080,
-								},
-							},
-						},
-					},
-					Volumes: []apiv1.Volume{
-						apiv1.Volume{
-							Name: "envoy-yaml",
-							VolumeSource: apiv1.VolumeSource{
-								ConfigMap: &apiv1.ConfigMapVolumeSource{
-									LocalObjectReference: apiv1.LocalObjectReference{
-										Name: envoy.Spec.ConfigMapName,
-									},
-								},
-							},
+							Name:         "envoy",
+							Image:        envoyImage(envoy),
+							Command:      []string{"envoy"},
+							Args:         []string{"-c", "/etc/envoy.yaml"},
+							VolumeMounts: []apiv1.VolumeMount{BootstrapVolumeMount()},
+							Ports:        ContainerPorts(listeners),
 						},
 					},
+					Volumes:      []apiv1.Volume{BootstrapVolume(envoy.Spec.ConfigMapName)},
+					NodeSelector: envoy.Spec.NodeSelector,
+					Tolerations:  envoy.Spec.Tolerations,
+				},
+			},
+		},
+	}
+	if envoy.Spec.Resources != nil {
+		deployment.Spec.Template.Spec.Containers[0].Resources = *envoy.Spec.Resources
+	}
+	return deployment, nil
+}
+
+// InlineListeners returns the listeners declared on an envoy as envoy listeners of its namespace. Their
+// names hold a colon, which no stored object's does, so they never clash with an EnvoyListener's.
+func InlineListeners(envoy *v1.Envoy) []*v1.EnvoyListener {
+	var out []*v1.EnvoyListener
+	for _, l := range envoy.Spec.Listeners {
+		out = append(out, &v1.EnvoyListener{
+			ObjectMeta: metav1.ObjectMeta{Namespace: envoy.Namespace, Name: envoy.Name + ":" + l.Name},
+			Spec: v1.EnvoyListenerSpec{
+				Port:        l.Port,
+				ServicePort: l.ServicePort,
+				Protocol:    l.Protocol,
+				Backend:     l.Backend,
+				TLSRoutes:   l.TLSRoutes,
+			},
+		})
+	}
+	return out
+}
+
+// rolloutStrategy returns a rolling update bounded by the envoy's rollout. Bounds it leaves out are the
+// 25% the api server would default, spelled out so the deployment's strategy can be compared exactly.
+func rolloutStrategy(envoy *v1.Envoy) appsv1.DeploymentStrategy {
+	defaultBound := intstr.FromString("25%")
+	maxSurge, maxUnavailable := &defaultBound, &defaultBound
+	if envoy.Spec.Rollout != nil {
+		if envoy.Spec.Rollout.MaxSurge != nil {
+			maxSurge = envoy.Spec.Rollout.MaxSurge
+		}
+		if envoy.Spec.Rollout.MaxUnavailable != nil {
+			maxUnavailable = envoy.Spec.Rollout.MaxUnavailable
+		}
+	}
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxSurge:       maxSurge,
+			MaxUnavailable: maxUnavailable,
+		},
+	}
+}
+
+// BootstrapVolume returns the volume holding the bootstrap config map of an envoy
+func BootstrapVolume(configMapName string) apiv1.Volume {
+	return apiv1.Volume{
+		Name: "envoy-yaml",
+		VolumeSource: apiv1.VolumeSource{
+			ConfigMap: &apiv1.ConfigMapVolumeSource{
+				LocalObjectReference: apiv1.LocalObjectReference{
+					Name: configMapName,
 				},
 			},
 		},
 	}
-	return deployment
 }
 
-//Service returns a spec for an envoy service
-func Service(envoy *v1.Envoy) *apiv1.Service {
+// BootstrapVolumeMount mounts the bootstrap volume where envoy is started with -c
+func BootstrapVolumeMount() apiv1.VolumeMount {
+	return apiv1.VolumeMount{
+		Name:      "envoy-yaml",
+		MountPath: "/etc/envoy.yaml",
+		SubPath:   "envoy.yaml",
+	}
+}
+
+// Service returns a spec for an envoy service exposing a port per listener
+func Service(envoy *v1.Envoy, listeners []*v1.EnvoyListener) *apiv1.Service {
 	service := &apiv1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: envoy.Spec.Name,
+			Name:            envoy.Spec.Name,
+			OwnerReferences: OwnerReferences(envoy),
 		},
 		Spec: apiv1.ServiceSpec{
-			Ports: []apiv1.ServicePort{
-				apiv1.ServicePort{
-					Name:       "http",
-					Protocol:   "TCP",
-					Port:       80,
-					TargetPort: intstr.IntOrString{IntVal: 8080},
-				},
-			},
-			Selector: map[string]string{
-				"app": "envoy",
-			},
+			Type:     apiv1.ServiceTypeClusterIP,
+			Ports:    ServicePorts(listeners),
+			Selector: Labels(envoy),
 		},
 	}
 	return service
 }
 
-func addAdminConfig() Admin {
+// Labels returns the labels of the pods of an envoy fleet, which its deployment and service select
+func Labels(envoy *v1.Envoy) map[string]string {
+	return map[string]string{
+		"app":      "envoy",
+		FleetLabel: envoy.Spec.Name,
+	}
+}
+
+// ContainerPorts returns the envoy container ports for listeners, http on 8080 when there are none
+func ContainerPorts(listeners []*v1.EnvoyListener) []apiv1.ContainerPort {
+	if len(listeners) == 0 {
+		return []apiv1.ContainerPort{
+			{
+				Name:          "http",
+				Protocol:      apiv1.ProtocolTCP,
+				ContainerPort: 8080,
+			},
+		}
+	}
+	var ports []apiv1.ContainerPort
+	for _, l := range listeners {
+		ports = append(ports, apiv1.ContainerPort{
+			Name:          portName(l),
+			Protocol:      apiv1.ProtocolTCP,
+			ContainerPort: l.Spec.Port,
+		})
+	}
+	return ports
+}
+
+// ServicePorts returns the envoy service ports for listeners, 80 to 8080 when there are none
+func ServicePorts(listeners []*v1.EnvoyListener) []apiv1.ServicePort {
+	if len(listeners) == 0 {
+		return []apiv1.ServicePort{
+			apiv1.ServicePort{
+				Name:       "http",
+				Protocol:   "TCP",
+				Port:       80,
+				TargetPort: intstr.IntOrString{IntVal: 8080},
+			},
+		}
+	}
+	var ports []apiv1.ServicePort
+	for _, l := range listeners {
+		ports = append(ports, apiv1.ServicePort{
+			Name:       portName(l),
+			Protocol:   apiv1.ProtocolTCP,
+			Port:       ListenerServicePort(l),
+			TargetPort: intstr.FromInt(int(l.Spec.Port)),
+		})
+	}
+	return ports
+}
+
+// ListenerServicePort returns the service port a listener is exposed on
+func ListenerServicePort(l *v1.EnvoyListener) int32 {
+	if l.Spec.ServicePort != 0 {
+		return l.Spec.ServicePort
+	}
+	return l.Spec.Port
+}
+
+func portName(l *v1.EnvoyListener) string {
+	return fmt.Sprintf("%s-%d", strings.ToLower(string(l.Spec.Protocol)), l.Spec.Port)
+}
+
+// envoyImage is the image of the envoy, or the controller's for a spec that was not defaulted
+func envoyImage(envoy *v1.Envoy) string {
+	if envoy.Spec.Image != "" {
+		return envoy.Spec.Image
+	}
+	return Image
+}
+
+func addAdminConfig(envoy *v1.Envoy) Admin {
+	port := AdminPort
+	if envoy.Spec.AdminPort != 0 {
+		port = int(envoy.Spec.AdminPort)
+	}
 	return Admin{
-		AccessLogPath: "/dev/stderr",
 		Address: Address{
 			SocketAddress: SocketAddress{
 				Address:   "127.0.0.1",
-				PortValue: 15000,
+				PortValue: port,
 			},
 		},
 	}
 }
 
 func addLDSConfig(envoy *v1.Envoy) LdsConfig {
-	clusterName := envoy.Spec.XDS.Name
+	clusterName := xdsConfig(envoy).Name
 	return LdsConfig{
 		APIConfigSource: APIConfigSource{
-			APIType: apiType,
+			APIType:             apiType,
+			TransportAPIVersion: apiVersion,
 			GrpcServices: GrpcServices{
 				Grpc: Grpc{
 					ClusterName: clusterName,
 				},
 			},
 		},
+		ResourceAPIVersion: apiVersion,
 	}
 }
 
 func addCDSConfig(envoy *v1.Envoy) CdsConfig {
-	clusterName := envoy.Spec.XDS.Name
+	clusterName := xdsConfig(envoy).Name
 	return CdsConfig{
 		APIConfigSource: APIConfigSource{
-			APIType: apiType,
+			APIType:             apiType,
+			TransportAPIVersion: apiVersion,
 			GrpcServices: GrpcServices{
 				Grpc: Grpc{
 					ClusterName: clusterName,
 				},
 			},
 		},
+		ResourceAPIVersion: apiVersion,
 	}
 }
 
 func addADSConfig(envoy *v1.Envoy) AdsConfig {
-	clusterName := envoy.Spec.XDS.Name
+	clusterName := xdsConfig(envoy).Name
 	return AdsConfig{
-		APIType: apiType,
+		APIType:             apiType,
+		TransportAPIVersion: apiVersion,
 		GrpcServices: GrpcServices{
 			Grpc: Grpc{
 				ClusterName: clusterName,
@@ -160,69 +313,138 @@ func addDynamicResources(envoy *v1.Envoy) DynamicResources {
 }
 
 func addStaticResources(envoy *v1.Envoy) StaticResources {
-	clusterName := envoy.Spec.XDS.Name
-	xdsHostname := envoy.Spec.XDS.Host
-	xdsPort := envoy.Spec.XDS.Port
+	xds := xdsConfig(envoy)
+	clusterName := xds.Name
+	xdsHostname := xds.Host
+	xdsPort := xds.Port
 	return StaticResources{
 		Clusters: []Clusters{{
 			Name:           clusterName,
 			ConnectTimeout: "5s",
 			Type:           "STRICT_DNS",
-			Hosts: []Hosts{{
-				SocketAddress: SocketAddress{
-					Address:   xdsHostname,
-					PortValue: xdsPort,
-				},
-			},
+			LoadAssignment: LoadAssignment{
+				ClusterName: clusterName,
+				Endpoints: []LocalityLbEndpoints{{
+					LbEndpoints: []LbEndpoints{{
+						Endpoint: Endpoint{
+							Address: Address{
+								SocketAddress: SocketAddress{
+									Address:   xdsHostname,
+									PortValue: xdsPort,
+								},
+							},
+						},
+					}},
+				}},
 			},
 		},
 		},
 	}
 }
 
+// xdsConfig returns the xds server an envoy should connect to
+func xdsConfig(envoy *v1.Envoy) v1.EnvoyXDS {
+	if envoy.Spec.XDS.BuiltIn {
+		return BuiltInXDS
+	}
+	return envoy.Spec.XDS
+}
+
+// OwnerReferences makes envoy the controller of a generated object so it is garbage collected with it.
+// Envoys that were never stored, like the one describing injected sidecars, own nothing.
+func OwnerReferences(envoy *v1.Envoy) []metav1.OwnerReference {
+	if envoy.UID == "" {
+		return nil
+	}
+	return []metav1.OwnerReference{
+		*metav1.NewControllerRef(envoy, v1.SchemeGroupVersion.WithKind("Envoy")),
+	}
+}
+
+// IsOwnedBy reports whether obj is controlled by envoy
+func IsOwnedBy(obj metav1.Object, envoy *v1.Envoy) bool {
+	ref := metav1.GetControllerOf(obj)
+	return ref != nil && ref.UID == envoy.UID
+}
+
+// NodeID returns the xds node id used by every proxy in an envoy fleet
+func NodeID(envoy *v1.Envoy) string {
+	return envoy.Namespace + "/" + envoy.Name
+}
+
 func makeEnvoyConfig(envoy *v1.Envoy) *Bootstrap {
 	envoyconfig := &Bootstrap{
 		Node: Node{
-			Cluster: "service_1",
-			ID:      "test-id",
+			Cluster: envoy.Spec.Name,
+			ID:      NodeID(envoy),
 		},
 
 		StaticResources:  addStaticResources(envoy),
 		DynamicResources: addDynamicResources(envoy),
-		Admin:            addAdminConfig(),
+		Admin:            addAdminConfig(envoy),
 	}
 	return envoyconfig
 }
 
-//ConfigMap returns a spec for an envoy bootstrap config
-func ConfigMap(envoy *v1.Envoy) *apiv1.ConfigMap {
-	var cfgData string
-	conf := makeEnvoyConfig(envoy)
+func renderBootstrap(envoy *v1.Envoy) (string, error) {
+	return render(envoy, makeEnvoyConfig(envoy))
+}
+
+func render(envoy *v1.Envoy, conf *Bootstrap) (string, error) {
 	jsonString, err := json.Marshal(conf)
 	if err != nil {
-		log.Println(err)
+		return "", fmt.Errorf("rendering bootstrap: %v", err)
 	}
+	if overrides := envoy.Spec.BootstrapOverrides; overrides != nil && len(overrides.Raw) > 0 {
+		patched, err := jsonpatch.MergePatch(jsonString, overrides.Raw)
+		if err != nil {
+			return "", fmt.Errorf("applying bootstrapOverrides: %v", err)
+		}
+		return string(patched), nil
+	}
+	return string(jsonString), nil
+}
 
-	cfgData = string(jsonString)
-	log.Println(cfgData)
+// BootstrapHash returns a short hash of the rendered bootstrap config
+func BootstrapHash(envoy *v1.Envoy) (string, error) {
+	bootstrap, err := renderBootstrap(envoy)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(bootstrap))
+	return hex.EncodeToString(sum[:])[:16], nil
+}
 
+// ConfigMap returns a spec for an envoy bootstrap config, or why the bootstrap could not be rendered
+func ConfigMap(envoy *v1.Envoy) (*apiv1.ConfigMap, error) {
+	cfgData, err := renderBootstrap(envoy)
+	if err != nil {
+		return nil, err
+	}
 	data := map[string]string{
 		"envoy.yaml": cfgData,
 	}
 	cfgMap := &apiv1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: envoy.Spec.ConfigMapName,
+			Name:            envoy.Spec.ConfigMapName,
+			OwnerReferences: OwnerReferences(envoy),
 		},
 		Data: data,
 	}
-	return cfgMap
+	return cfgMap, nil
 }
 
-//UpdateStatus updates the status of an envoy resource
-func UpdateStatus(clientset client.Interface, envoy *v1.Envoy, namespace string, deployment *appsv1.Deployment) error {
-	updatedObj := envoy.DeepCopy()
-	updatedObj.Status.AvailableReplicas = deployment.Status.AvailableReplicas
-	//TODO: use .UpdateStatus()? Might need a subresource
-	_, err := clientset.ExampleV1().Envoys(namespace).Update(updatedObj)
-	return err
+// SidecarConfigMap returns the bootstrap config map of the injected sidecars envoy describes. Their node
+// metadata marks them as sidecars, which the builtIn xds server serves the sidecar snapshot.
+func SidecarConfigMap(envoy *v1.Envoy) (*apiv1.ConfigMap, error) {
+	conf := makeEnvoyConfig(envoy)
+	conf.Node.Metadata = map[string]string{xds.SidecarMetadataKey: "true"}
+	cfgMap, err := ConfigMap(envoy)
+	if err != nil {
+		return nil, err
+	}
+	if cfgMap.Data["envoy.yaml"], err = render(envoy, conf); err != nil {
+		return nil, err
+	}
+	return cfgMap, nil
 }
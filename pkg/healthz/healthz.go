@@ -0,0 +1,105 @@
+package healthz
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stopTimeout is how long Run waits for probes in flight once stopped
+var stopTimeout = 5 * time.Second
+
+// Checker reports why a subsystem is unhealthy, or nil when it is healthy
+type Checker func() error
+
+// Checks is a set of named checkers served as one probe. It passes when every checker does.
+type Checks struct {
+	mu       sync.Mutex
+	checkers map[string]Checker
+}
+
+// Add registers check under name, replacing a checker registered before under the same name
+func (c *Checks) Add(name string, check Checker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.checkers == nil {
+		c.checkers = map[string]Checker{}
+	}
+	c.checkers[name] = check
+}
+
+// Check runs every checker and returns the failures by name
+func (c *Checks) Check() map[string]error {
+	c.mu.Lock()
+	checkers := make(map[string]Checker, len(c.checkers))
+	for name, check := range c.checkers {
+		checkers[name] = check
+	}
+	c.mu.Unlock()
+
+	failed := map[string]error{}
+	for name, check := range checkers {
+		if err := check(); err != nil {
+			failed[name] = err
+		}
+	}
+	return failed
+}
+
+// ServeHTTP answers 200 when every checker passes and 500 listing the failed ones otherwise
+func (c *Checks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	failed := c.Check()
+	if len(failed) == 0 {
+		fmt.Fprintln(w, "ok")
+		return
+	}
+	var lines []string
+	for name, err := range failed {
+		lines = append(lines, fmt.Sprintf("%s: %v", name, err))
+	}
+	sort.Strings(lines)
+	http.Error(w, strings.Join(lines, "\n"), http.StatusInternalServerError)
+}
+
+// Server serves the liveness probe on /healthz and the readiness probe on /readyz.
+// Subsystems add their checkers to Liveness and Readiness.
+type Server struct {
+	Liveness  *Checks
+	Readiness *Checks
+}
+
+// NewServer returns a probe server without any checkers, which passes both probes
+func NewServer() *Server {
+	return &Server{Liveness: &Checks{}, Readiness: &Checks{}}
+}
+
+// Run serves HTTP on addr until stopCh is closed
+func (s *Server) Run(addr string, stopCh <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", s.Liveness)
+	mux.Handle("/readyz", s.Readiness)
+	httpServer := &http.Server{Addr: addr, Handler: mux}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-stopCh
+		ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			slog.Error("Error stopping probe server", "err", err)
+		}
+	}()
+	slog.Info("Probe server listening", "address", addr)
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	<-stopped
+	return nil
+}
@@ -0,0 +1,302 @@
+package leader
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	coordinationinformers "k8s.io/client-go/informers/coordination/v1"
+	"k8s.io/client-go/kubernetes"
+	coordinationlisters "k8s.io/client-go/listers/coordination/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// ConnectionsLabel marks the connection records of the replicas competing for the lease it names
+	ConnectionsLabel = "envoy.starizard.io/xds-connections"
+	// ConnectionsAnnotation holds the open xds streams of a replica per node id, as a JSON object
+	ConnectionsAnnotation = "envoy.starizard.io/xds-connections"
+)
+
+// Connections shares the xds connections of every replica. Proxies connect to whichever replica the
+// service picks, so each replica publishes the streams open on it in a Lease of its own, named after
+// the election lease and its identity, renewed like the election lease. The leader adds the counts of
+// the other replicas to its own and deletes the records of replicas that stopped renewing them.
+type Connections struct {
+	kubeclientset kubernetes.Interface
+	elector       *Elector
+	// local returns the open streams on this replica per node id
+	local func() map[string]int
+	// OnChange, if set, is called with the node ids whose count on another replica changed
+	OnChange func(nodeID string)
+
+	informer cache.SharedIndexInformer
+	lister   coordinationlisters.LeaseNamespaceLister
+	changed  chan struct{}
+	now      func() time.Time
+
+	mu sync.Mutex
+	// published is what this replica last wrote to its record
+	published map[string]int
+}
+
+// NewConnections returns the connection records of the replicas competing for elector's lease, this
+// replica's counted by local
+func NewConnections(kubeclientset kubernetes.Interface, elector *Elector, local func() map[string]int) *Connections {
+	config := elector.config
+	informer := coordinationinformers.NewFilteredLeaseInformer(kubeclientset, config.Namespace, 0, cache.Indexers{},
+		func(options *metav1.ListOptions) {
+			options.LabelSelector = labels.SelectorFromSet(labels.Set{ConnectionsLabel: config.Name}).String()
+		})
+	c := &Connections{
+		kubeclientset: kubeclientset,
+		elector:       elector,
+		local:         local,
+		informer:      informer,
+		lister:        coordinationlisters.NewLeaseLister(informer.GetIndexer()).Leases(config.Namespace),
+		changed:       make(chan struct{}, 1),
+		now:           time.Now,
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.notify(nil, obj) },
+		UpdateFunc: func(old, cur interface{}) {
+			c.notify(old, cur)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			c.notify(obj, nil)
+		},
+	})
+	return c
+}
+
+// Changed tells the records that the streams open on this replica changed
+func (c *Connections) Changed() {
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Count returns the streams of nodeID open on the other replicas, as far as their records are fresh
+func (c *Connections) Count(nodeID string) int {
+	records, err := c.lister.List(labels.Everything())
+	if err != nil {
+		slog.Error("Error listing xds connection records", "err", err)
+		return 0
+	}
+	count := 0
+	for _, record := range records {
+		if c.own(record) || c.expired(record) {
+			continue
+		}
+		count += counts(record)[nodeID]
+	}
+	return count
+}
+
+// HasSynced reports whether the records of the other replicas have been listed
+func (c *Connections) HasSynced() bool {
+	return c.informer.HasSynced()
+}
+
+// Run watches the records of the other replicas and renews this replica's until stopCh is closed,
+// then deletes it
+func (c *Connections) Run(stopCh <-chan struct{}) {
+	go c.informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, c.informer.HasSynced) {
+		return
+	}
+	config := c.elector.config
+	// renewed as often as the election lease is retried, well within its duration
+	ticker := time.NewTicker(config.RetryPeriod)
+	defer ticker.Stop()
+	renewed := time.Time{}
+	for {
+		if err := c.sync(c.now().Sub(renewed) >= config.LeaseDuration/3); err != nil {
+			slog.Error("Error publishing xds connections", "err", err)
+		} else {
+			renewed = c.now()
+		}
+		select {
+		case <-stopCh:
+			c.release()
+			return
+		case <-c.changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync writes this replica's record when its counts changed or renew is set, and as the leader
+// deletes the records that expired
+func (c *Connections) sync(renew bool) error {
+	if c.elector.IsLeader() {
+		c.prune()
+	}
+	local := c.local()
+	c.mu.Lock()
+	unchanged := c.published != nil && equalCounts(c.published, local)
+	c.mu.Unlock()
+	if unchanged && !renew {
+		return nil
+	}
+	if err := c.publish(local); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.published = local
+	c.mu.Unlock()
+	return nil
+}
+
+// publish creates or renews the record of this replica with counts
+func (c *Connections) publish(counts map[string]int) error {
+	data, err := json.Marshal(counts)
+	if err != nil {
+		return err
+	}
+	config := c.elector.config
+	duration := int32(config.LeaseDuration / time.Second)
+	now := metav1.NewMicroTime(c.now())
+	leases := c.kubeclientset.CoordinationV1().Leases(config.Namespace)
+
+	current, err := c.lister.Get(c.recordName())
+	if errors.IsNotFound(err) {
+		_, err = leases.Create(&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        c.recordName(),
+				Namespace:   config.Namespace,
+				Labels:      map[string]string{ConnectionsLabel: config.Name},
+				Annotations: map[string]string{ConnectionsAnnotation: string(data)},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &config.Identity,
+				LeaseDurationSeconds: &duration,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	updated := current.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	updated.Annotations[ConnectionsAnnotation] = string(data)
+	updated.Spec.HolderIdentity = &config.Identity
+	updated.Spec.LeaseDurationSeconds = &duration
+	updated.Spec.RenewTime = &now
+	_, err = leases.Update(updated)
+	return err
+}
+
+// prune deletes the records of the replicas that stopped renewing them, which brings their
+// proxies' envoys back to reconcile through OnChange
+func (c *Connections) prune() {
+	records, err := c.lister.List(labels.Everything())
+	if err != nil {
+		slog.Error("Error listing xds connection records", "err", err)
+		return
+	}
+	leases := c.kubeclientset.CoordinationV1().Leases(c.elector.config.Namespace)
+	for _, record := range records {
+		if c.own(record) || !c.expired(record) {
+			continue
+		}
+		slog.Info("Deleting expired xds connection record", "lease", record.Name)
+		err := leases.Delete(record.Name, &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &record.UID}})
+		if err != nil && !errors.IsNotFound(err) && !errors.IsConflict(err) {
+			slog.Error("Error deleting expired xds connection record", "lease", record.Name, "err", err)
+		}
+	}
+}
+
+// release deletes the record of this replica, whose streams close as it shuts down
+func (c *Connections) release() {
+	err := c.kubeclientset.CoordinationV1().Leases(c.elector.config.Namespace).Delete(c.recordName(), &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		slog.Error("Error deleting xds connection record", "err", err)
+	}
+}
+
+// notify calls OnChange with the node ids whose count differs between two versions of a record
+func (c *Connections) notify(old, cur interface{}) {
+	if c.OnChange == nil {
+		return
+	}
+	var before, after map[string]int
+	if record, ok := old.(*coordinationv1.Lease); ok {
+		if c.own(record) {
+			return
+		}
+		before = counts(record)
+	}
+	if record, ok := cur.(*coordinationv1.Lease); ok {
+		if c.own(record) {
+			return
+		}
+		after = counts(record)
+	}
+	for nodeID, n := range before {
+		if after[nodeID] != n {
+			c.OnChange(nodeID)
+		}
+	}
+	for nodeID := range after {
+		if _, ok := before[nodeID]; !ok {
+			c.OnChange(nodeID)
+		}
+	}
+}
+
+func (c *Connections) recordName() string {
+	return c.elector.config.Name + "-xds-" + c.elector.config.Identity
+}
+
+func (c *Connections) own(record *coordinationv1.Lease) bool {
+	return record.Name == c.recordName()
+}
+
+// expired reports whether the replica behind record stopped renewing it
+func (c *Connections) expired(record *coordinationv1.Lease) bool {
+	spec := record.Spec
+	if spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second).Before(c.now())
+}
+
+// counts decodes the streams per node id of a record, a malformed one counts none
+func counts(record *coordinationv1.Lease) map[string]int {
+	counts := map[string]int{}
+	if data, ok := record.Annotations[ConnectionsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(data), &counts); err != nil {
+			slog.Warn("Ignoring malformed xds connection record", "lease", record.Name, "err", err)
+			return map[string]int{}
+		}
+	}
+	return counts
+}
+
+func equalCounts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
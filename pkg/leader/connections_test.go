@@ -0,0 +1,214 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+const testNode = "default/edge"
+
+func testConfig(identity string) Config {
+	return Config{
+		Name:          "kube-envoy-controller",
+		Namespace:     "default",
+		Identity:      identity,
+		LeaseDuration: 3 * time.Second,
+		RenewDeadline: 2 * time.Second,
+		RetryPeriod:   20 * time.Millisecond,
+	}
+}
+
+// eventually fails the test unless cond holds within a few seconds
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// localCounts stands in for the xds server of a replica
+type localCounts struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (l *localCounts) set(nodeID string, n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.counts = map[string]int{nodeID: n}
+}
+
+func (l *localCounts) get() map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := map[string]int{}
+	for k, v := range l.counts {
+		out[k] = v
+	}
+	return out
+}
+
+// changes collects the node ids OnChange is called with
+type changes struct {
+	mu    sync.Mutex
+	nodes map[string]int
+}
+
+func (c *changes) add(nodeID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nodes == nil {
+		c.nodes = map[string]int{}
+	}
+	c.nodes[nodeID]++
+}
+
+func (c *changes) count(nodeID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nodes[nodeID]
+}
+
+func TestElectorAcquiresAndReleases(t *testing.T) {
+	kubeclientset := kubefake.NewSimpleClientset()
+	e := NewElector(kubeclientset, testConfig("replica-a"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var led int32
+	done := make(chan error)
+	go func() {
+		done <- e.Run(ctx, func(ctx context.Context) {
+			atomic.StoreInt32(&led, 1)
+			<-ctx.Done()
+		})
+	}()
+	eventually(t, "the lease", func() bool { return e.IsLeader() && atomic.LoadInt32(&led) == 1 })
+	if e.Leader() != "replica-a" {
+		t.Fatalf("Leader = %q, want replica-a", e.Leader())
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if e.IsLeader() {
+		t.Fatal("still leading after Run returned")
+	}
+	lease, err := kubeclientset.CoordinationV1().Leases("default").Get("kube-envoy-controller", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder := lease.Spec.HolderIdentity; holder != nil && *holder != "" {
+		t.Fatalf("lease still held by %q after release", *holder)
+	}
+}
+
+func TestConnectionsAcrossReplicas(t *testing.T) {
+	kubeclientset := kubefake.NewSimpleClientset()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	leaderLocal, followerLocal := &localCounts{}, &localCounts{}
+	leaderElector := NewElector(kubeclientset, testConfig("replica-a"))
+	atomic.StoreInt32(&leaderElector.leading, 1)
+	leading := NewConnections(kubeclientset, leaderElector, leaderLocal.get)
+	seen := &changes{}
+	leading.OnChange = seen.add
+	following := NewConnections(kubeclientset, NewElector(kubeclientset, testConfig("replica-b")), followerLocal.get)
+	go leading.Run(stopCh)
+	go following.Run(stopCh)
+
+	leaderLocal.set(testNode, 1)
+	leading.Changed()
+	followerLocal.set(testNode, 2)
+	following.Changed()
+	eventually(t, "the follower's connections", func() bool { return leading.Count(testNode) == 2 })
+	if seen.count(testNode) == 0 {
+		t.Fatal("a connection on the follower did not change its node")
+	}
+	// the leader counts its own streams itself, never through its record
+	if n := following.Count(testNode); n != 1 {
+		t.Fatalf("follower counts %d streams on the leader, want 1", n)
+	}
+
+	followerLocal.set(testNode, 0)
+	following.Changed()
+	eventually(t, "the follower's closed streams", func() bool { return leading.Count(testNode) == 0 })
+}
+
+func TestConnectionsPrunesExpiredRecords(t *testing.T) {
+	config := testConfig("replica-gone")
+	renewed := metav1.NewMicroTime(time.Now().Add(-time.Minute))
+	duration := int32(3)
+	expired := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        config.Name + "-xds-" + config.Identity,
+			Namespace:   config.Namespace,
+			Labels:      map[string]string{ConnectionsLabel: config.Name},
+			Annotations: map[string]string{ConnectionsAnnotation: `{"default/edge":3}`},
+		},
+		Spec: coordinationv1.LeaseSpec{HolderIdentity: &config.Identity, LeaseDurationSeconds: &duration, RenewTime: &renewed},
+	}
+	kubeclientset := kubefake.NewSimpleClientset(expired)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	elector := NewElector(kubeclientset, testConfig("replica-a"))
+	leading := NewConnections(kubeclientset, elector, func() map[string]int { return nil })
+	seen := &changes{}
+	leading.OnChange = seen.add
+	go leading.Run(stopCh)
+
+	eventually(t, "the record to be listed", leading.HasSynced)
+	if n := leading.Count(testNode); n != 0 {
+		t.Fatalf("Count = %d from an expired record, want 0", n)
+	}
+	// only the leader deletes the records of other replicas
+	time.Sleep(100 * time.Millisecond)
+	if _, err := kubeclientset.CoordinationV1().Leases("default").Get(expired.Name, metav1.GetOptions{}); err != nil {
+		t.Fatalf("a follower deleted the expired record: %v", err)
+	}
+
+	atomic.StoreInt32(&elector.leading, 1)
+	leading.Changed()
+	eventually(t, "the expired record to be deleted", func() bool {
+		_, err := kubeclientset.CoordinationV1().Leases("default").Get(expired.Name, metav1.GetOptions{})
+		return err != nil
+	})
+	eventually(t, "the deletion to change the node", func() bool { return seen.count(testNode) >= 2 })
+}
+
+func TestConnectionsReleasedOnStop(t *testing.T) {
+	kubeclientset := kubefake.NewSimpleClientset()
+	stopCh := make(chan struct{})
+	local := &localCounts{}
+	local.set(testNode, 1)
+	connections := NewConnections(kubeclientset, NewElector(kubeclientset, testConfig("replica-b")), local.get)
+	done := make(chan struct{})
+	go func() {
+		connections.Run(stopCh)
+		close(done)
+	}()
+
+	leases := kubeclientset.CoordinationV1().Leases("default")
+	eventually(t, "the record to be published", func() bool {
+		_, err := leases.Get(connections.recordName(), metav1.GetOptions{})
+		return err == nil
+	})
+	close(stopCh)
+	<-done
+	if _, err := leases.Get(connections.recordName(), metav1.GetOptions{}); err == nil {
+		t.Fatal("the record outlived its replica")
+	}
+}
@@ -0,0 +1,116 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Config is the Lease controller replicas compete for and how eagerly they do
+type Config struct {
+	Name      string
+	Namespace string
+	// Identity names this replica in the lease, the hostname (pod name) by default
+	Identity string
+
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// DefaultConfig returns the lease settings used unless they are overridden
+func DefaultConfig() Config {
+	identity, _ := os.Hostname()
+	return Config{
+		Name:          "kube-envoy-controller",
+		Namespace:     "default",
+		Identity:      identity,
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+	}
+}
+
+// Elector campaigns for a Lease and runs a function for as long as it holds it
+type Elector struct {
+	kubeclientset kubernetes.Interface
+	config        Config
+	leading       int32
+	// leader is the identity holding the lease as last observed
+	leader atomic.Value
+}
+
+// NewElector returns an elector for the lease in config, managed with kubeclientset
+func NewElector(kubeclientset kubernetes.Interface, config Config) *Elector {
+	return &Elector{kubeclientset: kubeclientset, config: config}
+}
+
+// IsLeader reports whether this replica currently holds the lease
+func (e *Elector) IsLeader() bool {
+	return atomic.LoadInt32(&e.leading) == 1
+}
+
+// Leader returns the identity of the replica holding the lease, empty until it is known
+func (e *Elector) Leader() string {
+	leader, _ := e.leader.Load().(string)
+	return leader
+}
+
+// Run blocks campaigning for the lease and calls lead once it is acquired. The context passed to lead
+// is cancelled when the lease is lost. Run returns after leadership ended, or when ctx is cancelled
+// before it began; cancelling ctx while leading releases the lease so another replica takes over at once.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) error {
+	if e.config.Identity == "" {
+		return fmt.Errorf("leader election needs an identity")
+	}
+	lock, err := resourcelock.New(resourcelock.LeasesResourceLock,
+		e.config.Namespace,
+		e.config.Name,
+		e.kubeclientset.CoreV1(),
+		e.kubeclientset.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: e.config.Identity},
+	)
+	if err != nil {
+		return err
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   e.config.LeaseDuration,
+		RenewDeadline:   e.config.RenewDeadline,
+		RetryPeriod:     e.config.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            e.config.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				atomic.StoreInt32(&e.leading, 1)
+				slog.Info("Acquired lease", "identity", e.config.Identity, "namespace", e.config.Namespace, "lease", e.config.Name)
+				lead(ctx)
+			},
+			OnStoppedLeading: func() {
+				// also called when ctx ends before the lease was ever acquired
+				if !atomic.CompareAndSwapInt32(&e.leading, 1, 0) {
+					return
+				}
+				slog.Info("Stopped leading", "identity", e.config.Identity, "namespace", e.config.Namespace, "lease", e.config.Name)
+			},
+			OnNewLeader: func(identity string) {
+				e.leader.Store(identity)
+				if identity != e.config.Identity {
+					slog.Info("Lease held by another replica", "leader", identity, "namespace", e.config.Namespace, "lease", e.config.Name)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	elector.Run(ctx)
+	return nil
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Formats the controller can log in
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Setup makes a logger writing format at level and up to stderr the default slog logger. Messages
+// of the standard log package, e.g. from dependencies, go through it at info level.
+func Setup(format, level string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	options := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch format {
+	case FormatText:
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return fmt.Errorf("unknown log format %q, use %s or %s", format, FormatText, FormatJSON)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// ParseLevel reads one of debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(strings.ToUpper(level))); err != nil {
+		return lvl, fmt.Errorf("unknown log level %q, use debug, info, warn or error", level)
+	}
+	return lvl, nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying logger
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With returns a copy of ctx whose logger adds args to every message
+func With(ctx context.Context, args ...interface{}) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
+// NewID returns a short random id to tell the messages of one reconcile from another
+func NewID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "kube_envoy_controller"
+
+// Registry holds every metric the controller exposes, along with the go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	reconciles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_total",
+		Help:      "Syncs of envoys by result, success or error.",
+	}, []string{"result"})
+
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "How long syncs of envoys took by result, success or error.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"result"})
+
+	driftCorrections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drift_corrections_total",
+		Help:      "Generated objects put back into shape because they no longer matched their envoy, by kind.",
+	}, []string{"kind"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		reconciles,
+		reconcileDuration,
+		driftCorrections,
+	)
+}
+
+// ObserveReconcile counts a sync that started at start and ended with err
+func ObserveReconcile(start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	reconciles.WithLabelValues(result).Inc()
+	reconcileDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+// DriftCorrected counts a generated object of kind, e.g. deployment, that was updated to match its envoy
+func DriftCorrected(kind string) {
+	driftCorrections.WithLabelValues(kind).Inc()
+}
+
+// RegisterEnvoyCount reports the envoys count returns, keyed by namespace, on every scrape
+func RegisterEnvoyCount(count func() map[string]int) {
+	Registry.MustRegister(&gaugeFunc{
+		desc: prometheus.NewDesc(namespace+"_envoys", "Envoys managed by the controller per namespace.", []string{"namespace"}, nil),
+		values: func() map[string]float64 {
+			out := map[string]float64{}
+			for ns, n := range count() {
+				out[ns] = float64(n)
+			}
+			return out
+		},
+	})
+}
+
+// RegisterXDSVersions reports the snapshot versions versions returns, keyed by node id, on every scrape
+func RegisterXDSVersions(versions func() map[string]uint64) {
+	Registry.MustRegister(&gaugeFunc{
+		desc: prometheus.NewDesc(namespace+"_xds_snapshot_version", "Version of the xds snapshot served to each node.", []string{"node"}, nil),
+		values: func() map[string]float64 {
+			out := map[string]float64{}
+			for node, version := range versions() {
+				out[node] = float64(version)
+			}
+			return out
+		},
+	})
+}
+
+// Handler serves the metrics in Registry
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// gaugeFunc is a gauge with a single label whose values are read when scraped
+type gaugeFunc struct {
+	desc   *prometheus.Desc
+	values func() map[string]float64
+}
+
+func (g *gaugeFunc) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+func (g *gaugeFunc) Collect(ch chan<- prometheus.Metric) {
+	for label, value := range g.values() {
+		ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, value, label)
+	}
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// stopTimeout is how long Run waits for scrapes in flight once stopped
+var stopTimeout = 5 * time.Second
+
+// Server serves /metrics over plain HTTP
+type Server struct {
+	mux *http.ServeMux
+}
+
+// NewServer returns a server with the metrics handler registered under /metrics
+func NewServer() *Server {
+	s := &Server{mux: http.NewServeMux()}
+	s.mux.Handle("/metrics", Handler())
+	return s
+}
+
+// Handle registers another handler under path
+func (s *Server) Handle(path string, handler http.Handler) {
+	s.mux.Handle(path, handler)
+}
+
+// Run serves HTTP on addr until stopCh is closed
+func (s *Server) Run(addr string, stopCh <-chan struct{}) error {
+	httpServer := &http.Server{Addr: addr, Handler: s.mux}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-stopCh
+		ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			slog.Error("Error stopping metrics server", "err", err)
+		}
+	}()
+	slog.Info("Metrics server listening", "address", addr)
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	<-stopped
+	return nil
+}
@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// The workqueue metrics follow the names client-go's own provider uses, so the usual dashboards and
+// alerts work. They are only reported for named queues created after this package is imported.
+var (
+	depth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "workqueue",
+		Name:      "depth",
+		Help:      "Current depth of the workqueue.",
+	}, []string{"name"})
+
+	adds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "workqueue",
+		Name:      "adds_total",
+		Help:      "Keys added to the workqueue.",
+	}, []string{"name"})
+
+	latency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: "workqueue",
+		Name:      "queue_duration_seconds",
+		Help:      "How long a key stays in the workqueue before it is processed.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+	}, []string{"name"})
+
+	workDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: "workqueue",
+		Name:      "work_duration_seconds",
+		Help:      "How long processing a key from the workqueue takes.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+	}, []string{"name"})
+
+	unfinishedWork = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "workqueue",
+		Name:      "unfinished_work_seconds",
+		Help:      "Seconds of work in progress that has not been observed by work_duration yet. A large value that keeps growing points at stuck workers.",
+	}, []string{"name"})
+
+	longestRunning = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "workqueue",
+		Name:      "longest_running_processor_seconds",
+		Help:      "How long the longest running worker has been processing its key.",
+	}, []string{"name"})
+
+	retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "workqueue",
+		Name:      "retries_total",
+		Help:      "Keys requeued with a rate limit after a failed sync.",
+	}, []string{"name"})
+)
+
+func init() {
+	Registry.MustRegister(depth, adds, latency, workDuration, unfinishedWork, longestRunning, retries)
+	workqueue.SetProvider(workqueueProvider{})
+}
+
+// workqueueProvider hands the workqueue the metrics above; the deprecated ones are not reported
+type workqueueProvider struct{}
+
+func (workqueueProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return depth.WithLabelValues(name)
+}
+
+func (workqueueProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return adds.WithLabelValues(name)
+}
+
+func (workqueueProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return latency.WithLabelValues(name)
+}
+
+func (workqueueProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return workDuration.WithLabelValues(name)
+}
+
+func (workqueueProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return unfinishedWork.WithLabelValues(name)
+}
+
+func (workqueueProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return longestRunning.WithLabelValues(name)
+}
+
+func (workqueueProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return retries.WithLabelValues(name)
+}
+
+func (workqueueProvider) NewDeprecatedDepthMetric(name string) workqueue.GaugeMetric {
+	return noop{}
+}
+
+func (workqueueProvider) NewDeprecatedAddsMetric(name string) workqueue.CounterMetric {
+	return noop{}
+}
+
+func (workqueueProvider) NewDeprecatedLatencyMetric(name string) workqueue.SummaryMetric {
+	return noop{}
+}
+
+func (workqueueProvider) NewDeprecatedWorkDurationMetric(name string) workqueue.SummaryMetric {
+	return noop{}
+}
+
+func (workqueueProvider) NewDeprecatedUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noop{}
+}
+
+func (workqueueProvider) NewDeprecatedLongestRunningProcessorMicrosecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noop{}
+}
+
+func (workqueueProvider) NewDeprecatedRetriesMetric(name string) workqueue.CounterMetric {
+	return noop{}
+}
+
+type noop struct{}
+
+func (noop) Inc()            {}
+func (noop) Dec()            {}
+func (noop) Set(float64)     {}
+func (noop) Observe(float64) {}
@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// admitFunc reviews a single admission request
+type admitFunc func(req *v1beta1.AdmissionRequest) *v1beta1.AdmissionResponse
+
+// admissionHandler decodes an AdmissionReview, runs admit on its request and writes the review back.
+// The response echoes the apiVersion of the request so both admission.k8s.io/v1 and v1beta1 work.
+func admissionHandler(admit admitFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		review := v1beta1.AdmissionReview{}
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			http.Error(w, fmt.Sprintf("could not decode admission review: %v", err), http.StatusBadRequest)
+			return
+		}
+		if review.Request == nil {
+			http.Error(w, "admission review has no request", http.StatusBadRequest)
+			return
+		}
+
+		response := admit(review.Request)
+		response.UID = review.Request.UID
+		review.Response = response
+		review.Request = nil
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			slog.Error("Error writing admission response", "err", err)
+		}
+	})
+}
+
+func allowed() *v1beta1.AdmissionResponse {
+	return &v1beta1.AdmissionResponse{Allowed: true}
+}
+
+func denied(err error) *v1beta1.AdmissionResponse {
+	return &v1beta1.AdmissionResponse{
+		Allowed: false,
+		Result:  &metav1.Status{Message: err.Error()},
+	}
+}
@@ -0,0 +1,174 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/starizard/kube-envoy-controller/pkg/api/conversion"
+)
+
+// conversionReview is the apiextensions.k8s.io/v1 ConversionReview the API server sends to the
+// conversion webhook of a CRD, and expects back with the response filled in
+type conversionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *conversionRequest  `json:"request,omitempty"`
+	Response        *conversionResponse `json:"response,omitempty"`
+}
+
+type conversionRequest struct {
+	UID               types.UID              `json:"uid"`
+	DesiredAPIVersion string                 `json:"desiredAPIVersion"`
+	Objects           []runtime.RawExtension `json:"objects"`
+}
+
+type conversionResponse struct {
+	UID              types.UID              `json:"uid"`
+	ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+	Result           metav1.Status          `json:"result"`
+}
+
+// Converter converts custom resources between the versions registered in a scheme. Every kind has
+// one hub version, the others are spokes converting only to and from it; a spoke to spoke conversion
+// goes through the hub.
+type Converter struct {
+	scheme *runtime.Scheme
+}
+
+// NewConverter returns a converter for the kinds registered in scheme
+func NewConverter(scheme *runtime.Scheme) *Converter {
+	return &Converter{scheme: scheme}
+}
+
+// Handler returns the conversion webhook handler
+func (c *Converter) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		review := conversionReview{}
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			http.Error(w, fmt.Sprintf("could not decode conversion review: %v", err), http.StatusBadRequest)
+			return
+		}
+		if review.Request == nil {
+			http.Error(w, "conversion review has no request", http.StatusBadRequest)
+			return
+		}
+
+		response := c.convertAll(review.Request)
+		response.UID = review.Request.UID
+		review.Response = response
+		review.Request = nil
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			slog.Error("Error writing conversion response", "err", err)
+		}
+	})
+}
+
+// convertAll converts every object of req or none, the API server fails the whole request on any error
+func (c *Converter) convertAll(req *conversionRequest) *conversionResponse {
+	desired, err := schema.ParseGroupVersion(req.DesiredAPIVersion)
+	if err != nil {
+		return conversionFailed(err)
+	}
+	converted := make([]runtime.RawExtension, 0, len(req.Objects))
+	for _, obj := range req.Objects {
+		raw, err := c.convert(obj.Raw, desired)
+		if err != nil {
+			return conversionFailed(err)
+		}
+		converted = append(converted, runtime.RawExtension{Raw: raw})
+	}
+	return &conversionResponse{
+		ConvertedObjects: converted,
+		Result:           metav1.Status{Status: metav1.StatusSuccess},
+	}
+}
+
+func (c *Converter) convert(raw []byte, desired schema.GroupVersion) ([]byte, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, fmt.Errorf("could not decode object: %v", err)
+	}
+	gvk := typeMeta.GroupVersionKind()
+	if gvk.GroupVersion() == desired {
+		return raw, nil
+	}
+	src, err := c.scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, src); err != nil {
+		return nil, fmt.Errorf("could not decode %s: %v", gvk, err)
+	}
+	dst, err := c.scheme.New(desired.WithKind(gvk.Kind))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.convertObject(src, dst); err != nil {
+		return nil, err
+	}
+	dst.GetObjectKind().SetGroupVersionKind(desired.WithKind(gvk.Kind))
+	return json.Marshal(dst)
+}
+
+func (c *Converter) convertObject(src, dst runtime.Object) error {
+	switch s := src.(type) {
+	case conversion.Hub:
+		d, ok := dst.(conversion.Convertible)
+		if !ok {
+			return fmt.Errorf("%T cannot be converted from the hub", dst)
+		}
+		return d.ConvertFrom(s)
+	case conversion.Convertible:
+		if d, ok := dst.(conversion.Hub); ok {
+			return s.ConvertTo(d)
+		}
+		d, ok := dst.(conversion.Convertible)
+		if !ok {
+			return fmt.Errorf("%T cannot be converted from the hub", dst)
+		}
+		hub, err := c.hub(src.GetObjectKind().GroupVersionKind())
+		if err != nil {
+			return err
+		}
+		if err := s.ConvertTo(hub); err != nil {
+			return err
+		}
+		return d.ConvertFrom(hub)
+	}
+	return fmt.Errorf("%T is neither a hub nor convertible", src)
+}
+
+// hub returns a new object of the hub version of the kind of gvk
+func (c *Converter) hub(gvk schema.GroupVersionKind) (conversion.Hub, error) {
+	for known := range c.scheme.AllKnownTypes() {
+		if known.GroupKind() != gvk.GroupKind() {
+			continue
+		}
+		obj, err := c.scheme.New(known)
+		if err != nil {
+			return nil, err
+		}
+		if hub, ok := obj.(conversion.Hub); ok {
+			return hub, nil
+		}
+	}
+	return nil, fmt.Errorf("%s has no hub version", gvk.GroupKind())
+}
+
+func conversionFailed(err error) *conversionResponse {
+	return &conversionResponse{
+		Result: metav1.Status{Status: metav1.StatusFailure, Message: err.Error()},
+	}
+}
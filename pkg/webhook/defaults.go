@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"k8s.io/api/admission/v1beta1"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	envoyutils "github.com/starizard/kube-envoy-controller/pkg/envoy"
+)
+
+// Defaulter fills in the spec of envoys as they are created or updated, so that the stored object
+// shows the names, replicas, xds server, image and admin port its fleet actually runs with
+type Defaulter struct{}
+
+// NewDefaulter returns a defaulter taking the image and admin port from the controller config
+func NewDefaulter() *Defaulter {
+	return &Defaulter{}
+}
+
+// Handler returns the mutating webhook handler for envoy creation and updates
+func (d *Defaulter) Handler() http.Handler {
+	return admissionHandler(d.admit)
+}
+
+func (d *Defaulter) admit(req *v1beta1.AdmissionRequest) *v1beta1.AdmissionResponse {
+	if req.Kind.Kind != "Envoy" || (req.Operation != v1beta1.Create && req.Operation != v1beta1.Update) {
+		return allowed()
+	}
+	envoy := &v1.Envoy{}
+	if err := json.Unmarshal(req.Object.Raw, envoy); err != nil {
+		return denied(fmt.Errorf("could not decode envoy: %v", err))
+	}
+	if !envoyutils.Default(envoy) {
+		return allowed()
+	}
+
+	// the whole spec is replaced, the api server prunes and validates it like any other
+	patch, err := json.Marshal([]patchOperation{{Op: "add", Path: "/spec", Value: envoy.Spec}})
+	if err != nil {
+		return denied(err)
+	}
+	patchType := v1beta1.PatchTypeJSONPatch
+	return &v1beta1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patch,
+		PatchType: &patchType,
+	}
+}
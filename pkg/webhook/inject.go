@@ -0,0 +1,300 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/api/admission/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	envoyutils "github.com/starizard/kube-envoy-controller/pkg/envoy"
+	"github.com/starizard/kube-envoy-controller/pkg/xds"
+)
+
+const (
+	// InjectLabel opts a pod in ("true") or out ("false") of sidecar injection
+	InjectLabel = "sidecar.envoy.starizard.io/inject"
+	// LegacyInjectLabel is InjectLabel under the example.com group, still honored when InjectLabel is not set
+	LegacyInjectLabel = "sidecar.envoy.example.com/inject"
+	// NamespaceInjectLabel set to "enabled" on a namespace opts in all of its pods
+	NamespaceInjectLabel = "envoy-injection"
+
+	// SidecarConfigMapName is the bootstrap config map shared by the sidecars of a namespace
+	SidecarConfigMapName = "envoy-sidecar"
+	sidecarName          = "envoy-sidecar"
+	initName             = "envoy-init"
+
+	// sidecarUID runs envoy so the init rules can let its own traffic out untouched
+	sidecarUID = 1337
+)
+
+// InitImage provides iptables for the redirection init container. It runs with NET_ADMIN in every
+// injected pod, so it is expected pinned by digest; injection is off while it is empty.
+var InitImage = ""
+
+// Injector adds an envoy sidecar to pods that opt in through their labels or their namespace
+type Injector struct {
+	kubeclientset kubernetes.Interface
+}
+
+// NewInjector returns an injector creating sidecar bootstrap config maps with kubeclientset
+func NewInjector(kubeclientset kubernetes.Interface) *Injector {
+	return &Injector{kubeclientset: kubeclientset}
+}
+
+// Handler returns the mutating webhook handler for pod creation
+func (i *Injector) Handler() http.Handler {
+	return admissionHandler(i.admit)
+}
+
+func (i *Injector) admit(req *v1beta1.AdmissionRequest) *v1beta1.AdmissionResponse {
+	if req.Kind.Kind != "Pod" || req.Operation != v1beta1.Create {
+		return allowed()
+	}
+	pod := apiv1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, &pod); err != nil {
+		return denied(fmt.Errorf("could not decode pod: %v", err))
+	}
+	// pods created through a controller have no namespace of their own yet
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = pod.Namespace
+	}
+
+	inject, err := i.wantsSidecar(&pod, namespace)
+	if err != nil {
+		return denied(err)
+	}
+	if !inject {
+		return allowed()
+	}
+	if req.DryRun == nil || !*req.DryRun {
+		if err := i.ensureBootstrap(namespace); err != nil {
+			return denied(fmt.Errorf("could not create sidecar bootstrap: %v", err))
+		}
+	}
+
+	patch, err := json.Marshal(sidecarPatch(&pod))
+	if err != nil {
+		return denied(err)
+	}
+	patchType := v1beta1.PatchTypeJSONPatch
+	slog.Info("Injecting envoy sidecar", "namespace", namespace, "pod", podName(&pod))
+	return &v1beta1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patch,
+		PatchType: &patchType,
+	}
+}
+
+// wantsSidecar checks the pod label first so pods can opt out of an enabled namespace
+func (i *Injector) wantsSidecar(pod *apiv1.Pod, namespace string) (bool, error) {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == sidecarName {
+			return false, nil
+		}
+	}
+	inject, ok := pod.Labels[InjectLabel]
+	if !ok {
+		inject = pod.Labels[LegacyInjectLabel]
+	}
+	switch inject {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	ns, err := i.kubeclientset.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("could not read namespace %s: %v", namespace, err)
+	}
+	return ns.Labels[NamespaceInjectLabel] == "enabled", nil
+}
+
+// ensureBootstrap creates the namespace's sidecar bootstrap config map, or brings one written by an
+// older controller up to date
+func (i *Injector) ensureBootstrap(namespace string) error {
+	desired, err := envoyutils.SidecarConfigMap(sidecarEnvoy(namespace))
+	if err != nil {
+		return err
+	}
+	cfgClient := i.kubeclientset.CoreV1().ConfigMaps(namespace)
+	current, err := cfgClient.Get(SidecarConfigMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = cfgClient.Create(desired)
+		if errors.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+	if err != nil || reflect.DeepEqual(current.Data, desired.Data) {
+		return err
+	}
+	updated := current.DeepCopy()
+	updated.Data = desired.Data
+	_, err = cfgClient.Update(updated)
+	return err
+}
+
+// sidecarEnvoy describes the bootstrap of injected sidecars: connected to the builtIn xds server,
+// with node id and cluster overridden per pod on the envoy command line
+func sidecarEnvoy(namespace string) *v1.Envoy {
+	return &v1.Envoy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      SidecarConfigMapName,
+			Namespace: namespace,
+		},
+		Spec: v1.EnvoySpec{
+			Name:          sidecarName,
+			ConfigMapName: SidecarConfigMapName,
+			XDS:           v1.EnvoyXDS{BuiltIn: true},
+		},
+	}
+}
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+func sidecarPatch(pod *apiv1.Pod) []patchOperation {
+	var patch []patchOperation
+	patch = append(patch, appendPatch("/spec/initContainers", len(pod.Spec.InitContainers) == 0, initContainer(pod))...)
+	patch = append(patch, appendPatch("/spec/containers", len(pod.Spec.Containers) == 0, sidecarContainer(pod))...)
+	patch = append(patch, appendPatch("/spec/volumes", len(pod.Spec.Volumes) == 0, envoyutils.BootstrapVolume(SidecarConfigMapName))...)
+	return patch
+}
+
+// appendPatch adds value to the list at path, creating the list when the pod has none
+func appendPatch(path string, empty bool, value interface{}) []patchOperation {
+	if empty {
+		return []patchOperation{{Op: "add", Path: path, Value: []interface{}{value}}}
+	}
+	return []patchOperation{{Op: "add", Path: path + "/-", Value: value}}
+}
+
+func sidecarContainer(pod *apiv1.Pod) apiv1.Container {
+	uid := int64(sidecarUID)
+	return apiv1.Container{
+		Name:    sidecarName,
+		Image:   envoyutils.Image,
+		Command: []string{"envoy"},
+		Args: []string{
+			"-c", "/etc/envoy.yaml",
+			"--service-node", "$(POD_NAMESPACE)/$(POD_NAME)",
+			"--service-cluster", serviceCluster(pod),
+		},
+		Env: []apiv1.EnvVar{
+			fieldEnv("POD_NAME", "metadata.name"),
+			fieldEnv("POD_NAMESPACE", "metadata.namespace"),
+		},
+		VolumeMounts:    []apiv1.VolumeMount{envoyutils.BootstrapVolumeMount()},
+		SecurityContext: &apiv1.SecurityContext{RunAsUser: &uid},
+	}
+}
+
+// initContainer redirects inbound tcp to envoy's inbound port and outbound tcp, except envoy's own
+// and loopback traffic, to its outbound port. The kubelet's probes and envoy's admin port are left
+// alone so that a pod's health does not depend on its sidecar.
+func initContainer(pod *apiv1.Pod) apiv1.Container {
+	admin := envoyutils.AdminPort
+	rules := []string{"iptables -t nat -N ENVOY_INBOUND"}
+	for _, port := range append(probePorts(pod), int32(admin)) {
+		rules = append(rules, fmt.Sprintf("iptables -t nat -A ENVOY_INBOUND -p tcp --dport %d -j RETURN", port))
+	}
+	rules = append(rules,
+		fmt.Sprintf("iptables -t nat -A ENVOY_INBOUND -p tcp -j REDIRECT --to-port %d", xds.SidecarInboundPort),
+		"iptables -t nat -A PREROUTING -p tcp -j ENVOY_INBOUND",
+		"iptables -t nat -N ENVOY_OUTBOUND",
+		fmt.Sprintf("iptables -t nat -A ENVOY_OUTBOUND -m owner --uid-owner %d -j RETURN", sidecarUID),
+		"iptables -t nat -A ENVOY_OUTBOUND -d 127.0.0.1/32 -j RETURN",
+		fmt.Sprintf("iptables -t nat -A ENVOY_OUTBOUND -p tcp --dport %d -j RETURN", admin),
+		fmt.Sprintf("iptables -t nat -A ENVOY_OUTBOUND -p tcp -j REDIRECT --to-port %d", xds.SidecarOutboundPort),
+		"iptables -t nat -A OUTPUT -p tcp -j ENVOY_OUTBOUND",
+	)
+	return apiv1.Container{
+		Name:    initName,
+		Image:   InitImage,
+		Command: []string{"sh", "-c", strings.Join(rules, " && ")},
+		SecurityContext: &apiv1.SecurityContext{
+			Capabilities: &apiv1.Capabilities{Add: []apiv1.Capability{"NET_ADMIN"}},
+		},
+	}
+}
+
+// probePorts returns the ports the liveness and readiness probes of pod's containers connect to
+func probePorts(pod *apiv1.Pod) []int32 {
+	seen := map[int32]bool{}
+	var ports []int32
+	for _, c := range pod.Spec.Containers {
+		for _, probe := range []*apiv1.Probe{c.LivenessProbe, c.ReadinessProbe} {
+			if probe == nil {
+				continue
+			}
+			var port intstr.IntOrString
+			switch {
+			case probe.HTTPGet != nil:
+				port = probe.HTTPGet.Port
+			case probe.TCPSocket != nil:
+				port = probe.TCPSocket.Port
+			default:
+				continue
+			}
+			if p, ok := containerPort(c, port); ok && !seen[p] {
+				seen[p] = true
+				ports = append(ports, p)
+			}
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+	return ports
+}
+
+// containerPort resolves a probe port, which may name one of the container's ports
+func containerPort(c apiv1.Container, port intstr.IntOrString) (int32, bool) {
+	if port.Type == intstr.Int {
+		return port.IntVal, port.IntVal > 0
+	}
+	for _, p := range c.Ports {
+		if p.Name == port.StrVal {
+			return p.ContainerPort, true
+		}
+	}
+	return 0, false
+}
+
+func fieldEnv(name, fieldPath string) apiv1.EnvVar {
+	return apiv1.EnvVar{
+		Name: name,
+		ValueFrom: &apiv1.EnvVarSource{
+			FieldRef: &apiv1.ObjectFieldSelector{FieldPath: fieldPath},
+		},
+	}
+}
+
+// serviceCluster names the envoy cluster of a pod after its app label, falling back to the
+// workload name the pod was generated from
+func serviceCluster(pod *apiv1.Pod) string {
+	if app := pod.Labels["app"]; app != "" {
+		return app
+	}
+	return strings.TrimSuffix(podName(pod), "-")
+}
+
+func podName(pod *apiv1.Pod) string {
+	if pod.Name != "" {
+		return pod.Name
+	}
+	return pod.GenerateName
+}
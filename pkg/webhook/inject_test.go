@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/api/admission/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	envoyutils "github.com/starizard/kube-envoy-controller/pkg/envoy"
+	"github.com/starizard/kube-envoy-controller/pkg/xds"
+)
+
+const testInitImage = "registry.example/iptables@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func namespace(name string, labels map[string]string) *apiv1.Namespace {
+	return &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func testPod(labels map[string]string) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", Labels: labels},
+		Spec: apiv1.PodSpec{Containers: []apiv1.Container{{
+			Name:  "web",
+			Image: "nginx",
+			Ports: []apiv1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+			LivenessProbe: &apiv1.Probe{Handler: apiv1.Handler{
+				HTTPGet: &apiv1.HTTPGetAction{Path: "/healthz", Port: intstr.FromString("http")},
+			}},
+			ReadinessProbe: &apiv1.Probe{Handler: apiv1.Handler{
+				TCPSocket: &apiv1.TCPSocketAction{Port: intstr.FromInt(9090)},
+			}},
+		}}},
+	}
+}
+
+// review posts an AdmissionReview for obj to handler and returns the response
+func review(t *testing.T, handler http.Handler, kind string, obj runtime.Object, dryRun bool) *v1beta1.AdmissionResponse {
+	t.Helper()
+	return send(t, handler, &v1beta1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: kind},
+		Namespace: "shop",
+		Operation: v1beta1.Create,
+		Object:    raw(t, obj),
+		DryRun:    &dryRun,
+	})
+}
+
+// raw encodes obj as an admission request carries it
+func raw(t *testing.T, obj runtime.Object) runtime.RawExtension {
+	t.Helper()
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime.RawExtension{Raw: data}
+}
+
+// send posts an AdmissionReview of req to handler and returns the response
+func send(t *testing.T, handler http.Handler, req *v1beta1.AdmissionRequest) *v1beta1.AdmissionResponse {
+	t.Helper()
+	req.UID = "review"
+	body, err := json.Marshal(v1beta1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  req,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	out := v1beta1.AdmissionReview{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.APIVersion != "admission.k8s.io/v1" || out.Response == nil || out.Response.UID != "review" {
+		t.Fatalf("unexpected review %+v", out)
+	}
+	return out.Response
+}
+
+// patched applies the json patch of resp to pod
+func patched(t *testing.T, pod *apiv1.Pod, resp *v1beta1.AdmissionResponse) *apiv1.Pod {
+	t.Helper()
+	raw, err := json.Marshal(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, err := jsonpatch.DecodePatch(resp.Patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw, err = patch.Apply(raw); err != nil {
+		t.Fatal(err)
+	}
+	out := &apiv1.Pod{}
+	if err := json.Unmarshal(raw, out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestInjector(t *testing.T) {
+	InitImage = testInitImage
+	injected := testPod(nil)
+	injected.Spec.Containers = append(injected.Spec.Containers, apiv1.Container{Name: sidecarName})
+
+	tests := []struct {
+		name      string
+		namespace *apiv1.Namespace
+		kind      string
+		pod       *apiv1.Pod
+		dryRun    bool
+		inject    bool
+	}{
+		{name: "opted in", namespace: namespace("shop", nil), pod: testPod(map[string]string{InjectLabel: "true"}), inject: true},
+		{name: "legacy label", namespace: namespace("shop", nil), pod: testPod(map[string]string{LegacyInjectLabel: "true"}), inject: true},
+		{name: "enabled namespace", namespace: namespace("shop", map[string]string{NamespaceInjectLabel: "enabled"}), pod: testPod(nil), inject: true},
+		{name: "opted out of an enabled namespace", namespace: namespace("shop", map[string]string{NamespaceInjectLabel: "enabled"}), pod: testPod(map[string]string{InjectLabel: "false"})},
+		{name: "label wins over the legacy label", namespace: namespace("shop", nil), pod: testPod(map[string]string{InjectLabel: "false", LegacyInjectLabel: "true"})},
+		{name: "not opted in", namespace: namespace("shop", nil), pod: testPod(nil)},
+		{name: "already injected", namespace: namespace("shop", map[string]string{NamespaceInjectLabel: "enabled"}), pod: injected},
+		{name: "dry run", namespace: namespace("shop", nil), pod: testPod(map[string]string{InjectLabel: "true"}), dryRun: true, inject: true},
+		{name: "not a pod", namespace: namespace("shop", nil), kind: "Service", pod: testPod(map[string]string{InjectLabel: "true"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeclientset := kubefake.NewSimpleClientset(tt.namespace)
+			kind := tt.kind
+			if kind == "" {
+				kind = "Pod"
+			}
+			resp := review(t, NewInjector(kubeclientset).Handler(), kind, tt.pod, tt.dryRun)
+			if !resp.Allowed {
+				t.Fatalf("denied: %v", resp.Result)
+			}
+			if !tt.inject {
+				if resp.Patch != nil {
+					t.Fatalf("unexpected patch %s", resp.Patch)
+				}
+				return
+			}
+
+			pod := patched(t, tt.pod, resp)
+			if len(pod.Spec.Containers) != 2 || pod.Spec.Containers[1].Name != sidecarName {
+				t.Fatalf("got containers %v, want the sidecar appended", pod.Spec.Containers)
+			}
+			if len(pod.Spec.InitContainers) != 1 || pod.Spec.InitContainers[0].Image != testInitImage {
+				t.Fatalf("got init containers %v, want %s", pod.Spec.InitContainers, testInitImage)
+			}
+			if len(pod.Spec.Volumes) != 1 || pod.Spec.Volumes[0].ConfigMap.Name != SidecarConfigMapName {
+				t.Fatalf("got volumes %v, want the %s config map", pod.Spec.Volumes, SidecarConfigMapName)
+			}
+
+			_, err := kubeclientset.CoreV1().ConfigMaps("shop").Get(SidecarConfigMapName, metav1.GetOptions{})
+			if tt.dryRun && err == nil {
+				t.Fatal("dry run created the sidecar bootstrap")
+			}
+			if !tt.dryRun && err != nil {
+				t.Fatalf("sidecar bootstrap: %v", err)
+			}
+		})
+	}
+}
+
+func TestInjectorExcludesProbePorts(t *testing.T) {
+	InitImage = testInitImage
+	kubeclientset := kubefake.NewSimpleClientset(namespace("shop", nil))
+	pod := testPod(map[string]string{InjectLabel: "true"})
+	resp := review(t, NewInjector(kubeclientset).Handler(), "Pod", pod, false)
+
+	script := strings.Join(patched(t, pod, resp).Spec.InitContainers[0].Command, " ")
+	redirect := strings.Index(script, "ENVOY_INBOUND -p tcp -j REDIRECT")
+	for _, port := range []int{8080, 9090, envoyutils.AdminPort} {
+		rule := fmt.Sprintf("ENVOY_INBOUND -p tcp --dport %d -j RETURN", port)
+		at := strings.Index(script, rule)
+		if at < 0 || at > redirect {
+			t.Errorf("port %d is redirected to the sidecar: %s", port, script)
+		}
+	}
+	if !strings.Contains(script, fmt.Sprintf("--to-port %d", xds.SidecarOutboundPort)) {
+		t.Errorf("outbound traffic is not redirected to %d: %s", xds.SidecarOutboundPort, script)
+	}
+}
+
+func TestInjectorUpdatesBootstrap(t *testing.T) {
+	InitImage = testInitImage
+	stale, err := envoyutils.ConfigMap(sidecarEnvoy("shop"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kubeclientset := kubefake.NewSimpleClientset(namespace("shop", nil), stale)
+	review(t, NewInjector(kubeclientset).Handler(), "Pod", testPod(map[string]string{InjectLabel: "true"}), false)
+
+	cfgMap, err := kubeclientset.CoreV1().ConfigMaps("shop").Get(SidecarConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(cfgMap.Data["envoy.yaml"], xds.SidecarMetadataKey) {
+		t.Fatalf("sidecar bootstrap does not mark its node as a sidecar: %s", cfgMap.Data["envoy.yaml"])
+	}
+}
@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// stopTimeout is how long Run waits for requests in flight once stopped
+var stopTimeout = 5 * time.Second
+
+// Server serves the controller's admission webhooks over HTTPS
+type Server struct {
+	mux *http.ServeMux
+}
+
+// NewServer returns a webhook server without any handlers
+func NewServer() *Server {
+	return &Server{mux: http.NewServeMux()}
+}
+
+// Handle registers a webhook handler under path
+func (s *Server) Handle(path string, handler http.Handler) {
+	s.mux.Handle(path, handler)
+}
+
+// ServeHTTP dispatches to the registered handlers
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Run serves HTTPS on addr with the given certificate until stopCh is closed
+func (s *Server) Run(addr, certFile, keyFile string, stopCh <-chan struct{}) error {
+	httpServer := &http.Server{Addr: addr, Handler: s.mux}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-stopCh
+		ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			slog.Error("Error stopping webhook server", "err", err)
+		}
+	}()
+	slog.Info("Webhook server listening", "address", addr)
+	if err := httpServer.ListenAndServeTLS(certFile, keyFile); err != http.ErrServerClosed {
+		return err
+	}
+	// requests in flight are still finishing
+	<-stopped
+	return nil
+}
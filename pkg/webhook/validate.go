@@ -0,0 +1,351 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"k8s.io/api/admission/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	client "github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned"
+	envoyutils "github.com/starizard/kube-envoy-controller/pkg/envoy"
+)
+
+// Validator rejects envoys the CRD schema cannot: ones that would take over the deployment, service or
+// config map of another envoy in their namespace, that rename their generated objects, or whose
+// proxies could never reach their xds server. It also rejects routes and listeners whose backends
+// name a port their service does not expose.
+type Validator struct {
+	clientset     client.Interface
+	kubeclientset kubernetes.Interface
+}
+
+// NewValidator returns a validator looking up other envoys with clientset and services with kubeclientset
+func NewValidator(clientset client.Interface, kubeclientset kubernetes.Interface) *Validator {
+	return &Validator{clientset: clientset, kubeclientset: kubeclientset}
+}
+
+// Handler returns the validating webhook handler for envoy, route and listener creation and updates
+func (v *Validator) Handler() http.Handler {
+	return admissionHandler(v.admit)
+}
+
+func (v *Validator) admit(req *v1beta1.AdmissionRequest) *v1beta1.AdmissionResponse {
+	if req.Operation != v1beta1.Create && req.Operation != v1beta1.Update {
+		return allowed()
+	}
+	var errs field.ErrorList
+	var err error
+	switch req.Kind.Kind {
+	case "Envoy":
+		errs, err = v.admitEnvoy(req)
+	case "EnvoyRoute":
+		errs, err = v.admitRoute(req)
+	case "EnvoyListener":
+		errs, err = v.admitListener(req)
+	default:
+		return allowed()
+	}
+	if err != nil {
+		return denied(err)
+	}
+	if len(errs) > 0 {
+		return denied(errs.ToAggregate())
+	}
+	return allowed()
+}
+
+func (v *Validator) admitEnvoy(req *v1beta1.AdmissionRequest) (field.ErrorList, error) {
+	envoy := &v1.Envoy{}
+	if err := json.Unmarshal(req.Object.Raw, envoy); err != nil {
+		return nil, fmt.Errorf("could not decode envoy: %v", err)
+	}
+	if envoy.Namespace == "" {
+		envoy.Namespace = req.Namespace
+	}
+	var old *v1.Envoy
+	if req.Operation == v1beta1.Update {
+		old = &v1.Envoy{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return nil, fmt.Errorf("could not decode old envoy: %v", err)
+		}
+		envoyutils.Default(old)
+	}
+	// without the defaulting webhook the controller writes the defaults later, validate what they will be
+	envoyutils.Default(envoy)
+	return v.validate(envoy, old)
+}
+
+// admitRoute checks the backends of a route whose spec is created or changed. The controller writes
+// route status with plain updates, which leave the spec alone and always pass.
+func (v *Validator) admitRoute(req *v1beta1.AdmissionRequest) (field.ErrorList, error) {
+	route, old := &v1.EnvoyRoute{}, &v1.EnvoyRoute{}
+	if err := json.Unmarshal(req.Object.Raw, route); err != nil {
+		return nil, fmt.Errorf("could not decode envoy route: %v", err)
+	}
+	if req.Operation == v1beta1.Update {
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return nil, fmt.Errorf("could not decode old envoy route: %v", err)
+		}
+		if route.DeletionTimestamp != nil || reflect.DeepEqual(route.Spec, old.Spec) {
+			return nil, nil
+		}
+	}
+	var backends []backendRef
+	hosts := field.NewPath("spec").Child("virtualHosts")
+	for i, vh := range route.Spec.VirtualHosts {
+		for j, rt := range vh.Routes {
+			for k, b := range rt.Backends {
+				backends = append(backends, backendRef{hosts.Index(i).Child("routes").Index(j).Child("backends").Index(k), b})
+			}
+		}
+	}
+	return v.validateBackends(namespaceOf(route.Namespace, req), backends), nil
+}
+
+// admitListener checks the backends of a listener whose spec is created or changed
+func (v *Validator) admitListener(req *v1beta1.AdmissionRequest) (field.ErrorList, error) {
+	listener, old := &v1.EnvoyListener{}, &v1.EnvoyListener{}
+	if err := json.Unmarshal(req.Object.Raw, listener); err != nil {
+		return nil, fmt.Errorf("could not decode envoy listener: %v", err)
+	}
+	if req.Operation == v1beta1.Update {
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return nil, fmt.Errorf("could not decode old envoy listener: %v", err)
+		}
+		if listener.DeletionTimestamp != nil || reflect.DeepEqual(listener.Spec, old.Spec) {
+			return nil, nil
+		}
+	}
+	backends := listenerBackends(field.NewPath("spec"), listener.Spec.Backend, listener.Spec.TLSRoutes)
+	return v.validateBackends(namespaceOf(listener.Namespace, req), backends), nil
+}
+
+// validate checks a created envoy, or an updated one against old. Updates only have what they
+// change checked, so that the controller can still release an envoy created before the webhook.
+func (v *Validator) validate(envoy, old *v1.Envoy) (field.ErrorList, error) {
+	if old != nil && (envoy.DeletionTimestamp != nil || reflect.DeepEqual(envoy.Spec, old.Spec)) {
+		return nil, nil
+	}
+	spec := field.NewPath("spec")
+	var errs field.ErrorList
+
+	nameChanged := old == nil || envoy.Spec.Name != old.Spec.Name
+	configMapChanged := old == nil || envoy.Spec.ConfigMapName != old.Spec.ConfigMapName
+	// renaming would orphan the generated objects
+	if old != nil && nameChanged {
+		errs = append(errs, field.Forbidden(spec.Child("name"), fmt.Sprintf("is immutable, was %q", old.Spec.Name)))
+		nameChanged = false
+	}
+	if old != nil && configMapChanged {
+		errs = append(errs, field.Forbidden(spec.Child("configMapName"), fmt.Sprintf("is immutable, was %q", old.Spec.ConfigMapName)))
+		configMapChanged = false
+	}
+	if configMapChanged && envoy.Spec.ConfigMapName == SidecarConfigMapName {
+		errs = append(errs, field.Invalid(spec.Child("configMapName"), envoy.Spec.ConfigMapName, "is reserved for the bootstrap of injected sidecars"))
+		configMapChanged = false
+	}
+
+	if nameChanged || configMapChanged {
+		collisions, err := v.collisions(envoy, nameChanged, configMapChanged)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, collisions...)
+	}
+	if old == nil || !reflect.DeepEqual(envoy.Spec.XDS, old.Spec.XDS) {
+		xdsErrs, err := v.validateXDS(envoy, spec.Child("xds"))
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, xdsErrs...)
+	}
+	if old == nil || !reflect.DeepEqual(envoy.Spec.Listeners, old.Spec.Listeners) {
+		var backends []backendRef
+		for i, l := range envoy.Spec.Listeners {
+			backends = append(backends, listenerBackends(spec.Child("listeners").Index(i), l.Backend, l.TLSRoutes)...)
+		}
+		errs = append(errs, v.validateBackends(envoy.Namespace, backends)...)
+	}
+	return errs, nil
+}
+
+// collisions reports the other envoys in the namespace that generate objects under the same names.
+// Envoys being deleted are skipped, their objects are handed over to a successor with the same names.
+func (v *Validator) collisions(envoy *v1.Envoy, checkName, checkConfigMap bool) (field.ErrorList, error) {
+	envoys, err := v.clientset.EnvoyV1().Envoys(envoy.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not list envoys in %s: %v", envoy.Namespace, err)
+	}
+	spec := field.NewPath("spec")
+	var errs field.ErrorList
+	for _, other := range envoys.Items {
+		if other.Name == envoy.Name || other.DeletionTimestamp != nil {
+			continue
+		}
+		envoyutils.Default(&other)
+		if checkName && other.Spec.Name == envoy.Spec.Name {
+			errs = append(errs, field.Invalid(spec.Child("name"), envoy.Spec.Name,
+				fmt.Sprintf("deployment and service are already generated for envoy %s", other.Name)))
+		}
+		if checkConfigMap && other.Spec.ConfigMapName == envoy.Spec.ConfigMapName {
+			errs = append(errs, field.Invalid(spec.Child("configMapName"), envoy.Spec.ConfigMapName,
+				fmt.Sprintf("config map is already generated for envoy %s", other.Name)))
+		}
+	}
+	return errs, nil
+}
+
+// validateXDS rejects xds servers the proxies cannot reach: loopback addresses, which point a pod at
+// itself, hosts that are not a DNS name or IP, and ports a service of the cluster does not expose.
+// A service that does not exist yet, or cannot be read, is let through; it may be created after the envoy.
+func (v *Validator) validateXDS(envoy *v1.Envoy, path *field.Path) (field.ErrorList, error) {
+	xds := envoy.Spec.XDS
+	if xds.BuiltIn {
+		return nil, nil
+	}
+	var errs field.ErrorList
+	if xds.Port < 1 || xds.Port > 65535 {
+		errs = append(errs, field.Invalid(path.Child("port"), xds.Port, "must be between 1 and 65535"))
+	}
+	host := path.Child("host")
+	switch {
+	case xds.Host == "":
+		return append(errs, field.Required(host, "is required with a port unless builtIn is set")), nil
+	case xds.Host == "localhost" || strings.HasSuffix(xds.Host, ".localhost"):
+		return append(errs, field.Invalid(host, xds.Host, "points every proxy at its own pod")), nil
+	}
+	if ip := net.ParseIP(xds.Host); ip != nil {
+		if ip.IsLoopback() || ip.IsUnspecified() {
+			errs = append(errs, field.Invalid(host, xds.Host, "points every proxy at its own pod"))
+		}
+		return errs, nil
+	}
+	if msgs := validation.IsDNS1123Subdomain(xds.Host); len(msgs) > 0 {
+		return append(errs, field.Invalid(host, xds.Host, strings.Join(msgs, ", "))), nil
+	}
+	if len(errs) > 0 {
+		return errs, nil
+	}
+
+	namespace, name, ok := clusterService(xds.Host, envoy.Namespace)
+	if !ok {
+		return nil, nil
+	}
+	svc, err := v.kubeclientset.CoreV1().Services(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			slog.Warn("Could not read xds service", "namespace", namespace, "service", name, "err", err)
+		}
+		return nil, nil
+	}
+	for _, port := range svc.Spec.Ports {
+		if int(port.Port) == xds.Port {
+			return nil, nil
+		}
+	}
+	return field.ErrorList{field.Invalid(path.Child("port"), xds.Port,
+		fmt.Sprintf("service %s/%s does not expose it, its ports are %s", namespace, name, servicePorts(svc.Spec.Ports)))}, nil
+}
+
+// backendRef is a backend of a route or listener and where it sits in the spec
+type backendRef struct {
+	path    *field.Path
+	backend v1.RouteBackend
+}
+
+// listenerBackends returns the backends of a listener spec at path
+func listenerBackends(path *field.Path, backend *v1.RouteBackend, tlsRoutes []v1.TLSRoute) []backendRef {
+	var backends []backendRef
+	if backend != nil {
+		backends = append(backends, backendRef{path.Child("backend"), *backend})
+	}
+	for i, r := range tlsRoutes {
+		backends = append(backends, backendRef{path.Child("tlsRoutes").Index(i).Child("backend"), r.Backend})
+	}
+	return backends
+}
+
+// validateBackends rejects backends naming a port, by number or name, that their service does not
+// expose. Like the xds service, a service that does not exist yet or cannot be read is let through.
+func (v *Validator) validateBackends(namespace string, backends []backendRef) field.ErrorList {
+	services := map[string]*apiv1.Service{}
+	var errs field.ErrorList
+	for _, ref := range backends {
+		name := ref.backend.Service
+		svc, seen := services[name]
+		if !seen {
+			var err error
+			svc, err = v.kubeclientset.CoreV1().Services(namespace).Get(name, metav1.GetOptions{})
+			if err != nil {
+				if !errors.IsNotFound(err) {
+					slog.Warn("Could not read backend service", "namespace", namespace, "service", name, "err", err)
+				}
+				svc = nil
+			}
+			services[name] = svc
+		}
+		if svc == nil || exposes(svc, ref.backend.Port) {
+			continue
+		}
+		errs = append(errs, field.Invalid(ref.path.Child("port"), ref.backend.Port.String(),
+			fmt.Sprintf("service %s/%s does not expose it, its ports are %s", namespace, name, servicePorts(svc.Spec.Ports))))
+	}
+	return errs
+}
+
+// exposes reports whether svc has a port with the number or name of port
+func exposes(svc *apiv1.Service, port intstr.IntOrString) bool {
+	for _, p := range svc.Spec.Ports {
+		if port.Type == intstr.Int && p.Port == port.IntVal || port.Type == intstr.String && p.Name == port.StrVal {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceOf returns the namespace of an admitted object, which only the request carries on create
+func namespaceOf(namespace string, req *v1beta1.AdmissionRequest) string {
+	if namespace == "" {
+		return req.Namespace
+	}
+	return namespace
+}
+
+// clusterService returns the service a host resolves to inside the cluster: a bare name in the
+// envoy's namespace, or name.namespace, optionally followed by .svc and the cluster domain
+func clusterService(host, envoyNamespace string) (string, string, bool) {
+	labels := strings.Split(host, ".")
+	switch {
+	case len(labels) == 1:
+		return envoyNamespace, labels[0], true
+	case len(labels) == 2, len(labels) >= 3 && labels[2] == "svc":
+		return labels[1], labels[0], true
+	}
+	return "", "", false
+}
+
+func servicePorts(ports []apiv1.ServicePort) string {
+	var out []string
+	for _, p := range ports {
+		if p.Name != "" {
+			out = append(out, fmt.Sprintf("%d (%s)", p.Port, p.Name))
+			continue
+		}
+		out = append(out, strconv.Itoa(int(p.Port)))
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,190 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/api/admission/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	"github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/fake"
+)
+
+// the fake tracker guesses "envoies" from the kind, the generated fake client lists "envoys"
+var envoysResource = schema.GroupVersionResource{Group: v1.SchemeGroupVersion.Group, Version: "v1", Resource: "envoys"}
+
+func validatedEnvoy(name string, edit func(e *v1.Envoy)) *v1.Envoy {
+	envoy := &v1.Envoy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+		Spec:       v1.EnvoySpec{Name: name, ConfigMapName: name, XDS: v1.EnvoyXDS{BuiltIn: true}},
+	}
+	if edit != nil {
+		edit(envoy)
+	}
+	return envoy
+}
+
+func service(name string, ports ...apiv1.ServicePort) *apiv1.Service {
+	return &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+		Spec:       apiv1.ServiceSpec{Ports: ports},
+	}
+}
+
+func externalXDS(host string, port int) func(e *v1.Envoy) {
+	return func(e *v1.Envoy) { e.Spec.XDS = v1.EnvoyXDS{Host: host, Port: port} }
+}
+
+func routeTo(port intstr.IntOrString) *v1.EnvoyRoute {
+	return &v1.EnvoyRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: v1.EnvoyRouteSpec{VirtualHosts: []v1.VirtualHost{{
+			Name:    "web",
+			Domains: []string{"*"},
+			Routes:  []v1.Route{{Backends: []v1.RouteBackend{{Service: "web", Port: port}}}},
+		}}},
+	}
+}
+
+func listenerTo(port intstr.IntOrString) *v1.EnvoyListener {
+	return &v1.EnvoyListener{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "shop"},
+		Spec: v1.EnvoyListenerSpec{
+			Port:     5432,
+			Protocol: v1.ListenerTCP,
+			Backend:  &v1.RouteBackend{Service: "db", Port: port},
+		},
+	}
+}
+
+func TestValidator(t *testing.T) {
+	deleting := validatedEnvoy("old-edge", func(e *v1.Envoy) {
+		now := metav1.Now()
+		e.DeletionTimestamp = &now
+		e.Spec.Name, e.Spec.ConfigMapName = "edge", "edge"
+	})
+	tls := listenerTo(intstr.FromInt(443))
+	tls.Spec.Protocol, tls.Spec.Backend = v1.ListenerTLS, nil
+	tls.Spec.TLSRoutes = []v1.TLSRoute{{ServerNames: []string{"db.example"}, Backend: v1.RouteBackend{Service: "db", Port: intstr.FromInt(5433)}}}
+	routeStatus := routeTo(intstr.FromInt(9090))
+	routeStatus.Status.Envoys = []v1.RouteEnvoyStatus{{Name: "edge", Accepted: true}}
+
+	tests := []struct {
+		name     string
+		kind     string
+		obj      runtime.Object
+		old      runtime.Object
+		envoys   []*v1.Envoy
+		services []runtime.Object
+		// denied is a substring of the denial, empty when the object is allowed
+		denied string
+	}{
+		{name: "envoy", kind: "Envoy", obj: validatedEnvoy("edge", nil), envoys: []*v1.Envoy{validatedEnvoy("internal", nil)}},
+		{name: "name collision", kind: "Envoy", denied: "spec.name",
+			obj:    validatedEnvoy("edge", func(e *v1.Envoy) { e.Spec.ConfigMapName = "edge-config" }),
+			envoys: []*v1.Envoy{validatedEnvoy("other", func(e *v1.Envoy) { e.Spec.Name = "edge" })}},
+		{name: "config map collision", kind: "Envoy", denied: "spec.configMapName",
+			obj:    validatedEnvoy("edge", nil),
+			envoys: []*v1.Envoy{validatedEnvoy("other", func(e *v1.Envoy) { e.Spec.ConfigMapName = "edge" })}},
+		{name: "defaulted names collide", kind: "Envoy", denied: "spec.name",
+			obj:    validatedEnvoy("edge", func(e *v1.Envoy) { e.Spec.Name, e.Spec.ConfigMapName = "", "" }),
+			envoys: []*v1.Envoy{validatedEnvoy("other", func(e *v1.Envoy) { e.Spec.Name = "edge" })}},
+		{name: "successor of an envoy being deleted", kind: "Envoy", obj: validatedEnvoy("edge", nil), envoys: []*v1.Envoy{deleting}},
+		{name: "reserved config map", kind: "Envoy", denied: "reserved",
+			obj: validatedEnvoy("edge", func(e *v1.Envoy) { e.Spec.ConfigMapName = SidecarConfigMapName })},
+		{name: "renamed", kind: "Envoy", denied: "spec.name: Forbidden",
+			obj: validatedEnvoy("edge", func(e *v1.Envoy) { e.Spec.Name = "edge-2" }), old: validatedEnvoy("edge", nil)},
+		{name: "config map renamed", kind: "Envoy", denied: "spec.configMapName: Forbidden",
+			obj: validatedEnvoy("edge", func(e *v1.Envoy) { e.Spec.ConfigMapName = "edge-2" }), old: validatedEnvoy("edge", nil)},
+		{name: "unchanged spec of a colliding envoy", kind: "Envoy",
+			obj: validatedEnvoy("edge", func(e *v1.Envoy) { e.Finalizers = []string{"done"} }), old: validatedEnvoy("edge", nil),
+			envoys: []*v1.Envoy{validatedEnvoy("other", func(e *v1.Envoy) { e.Spec.Name = "edge" })}},
+		{name: "loopback xds", kind: "Envoy", denied: "its own pod", obj: validatedEnvoy("edge", externalXDS("127.0.0.1", 18000))},
+		{name: "unspecified xds", kind: "Envoy", denied: "its own pod", obj: validatedEnvoy("edge", externalXDS("::", 18000))},
+		{name: "localhost xds", kind: "Envoy", denied: "its own pod", obj: validatedEnvoy("edge", externalXDS("localhost", 18000))},
+		{name: "invalid xds host", kind: "Envoy", denied: "spec.xds.host", obj: validatedEnvoy("edge", externalXDS("xds_server", 18000))},
+		{name: "missing xds host", kind: "Envoy", denied: "spec.xds.host: Required", obj: validatedEnvoy("edge", externalXDS("", 18000))},
+		{name: "xds port out of range", kind: "Envoy", denied: "spec.xds.port", obj: validatedEnvoy("edge", externalXDS("10.0.0.1", 70000))},
+		{name: "xds port not exposed", kind: "Envoy", denied: "service shop/xds does not expose it",
+			obj: validatedEnvoy("edge", externalXDS("xds", 18000)), services: []runtime.Object{service("xds", apiv1.ServicePort{Port: 8080})}},
+		{name: "xds port of another namespace", kind: "Envoy", denied: "service infra/xds",
+			obj: validatedEnvoy("edge", externalXDS("xds.infra.svc", 18000)),
+			services: []runtime.Object{&apiv1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "xds", Namespace: "infra"},
+				Spec:       apiv1.ServiceSpec{Ports: []apiv1.ServicePort{{Port: 8080}}},
+			}}},
+		{name: "xds port exposed", kind: "Envoy",
+			obj: validatedEnvoy("edge", externalXDS("xds", 18000)), services: []runtime.Object{service("xds", apiv1.ServicePort{Port: 18000})}},
+		{name: "xds service not created yet", kind: "Envoy", obj: validatedEnvoy("edge", externalXDS("xds", 18000))},
+		{name: "external xds", kind: "Envoy", obj: validatedEnvoy("edge", externalXDS("xds.example.com", 18000))},
+
+		{name: "route port number", kind: "EnvoyRoute", obj: routeTo(intstr.FromInt(8080)),
+			services: []runtime.Object{service("web", apiv1.ServicePort{Name: "http", Port: 8080})}},
+		{name: "route port name", kind: "EnvoyRoute", obj: routeTo(intstr.FromString("http")),
+			services: []runtime.Object{service("web", apiv1.ServicePort{Name: "http", Port: 8080})}},
+		{name: "route port not exposed", kind: "EnvoyRoute", obj: routeTo(intstr.FromInt(9090)),
+			denied:   "spec.virtualHosts[0].routes[0].backends[0].port",
+			services: []runtime.Object{service("web", apiv1.ServicePort{Name: "http", Port: 8080})}},
+		{name: "route port name not exposed", kind: "EnvoyRoute", obj: routeTo(intstr.FromString("grpc")),
+			denied:   "its ports are 8080 (http)",
+			services: []runtime.Object{service("web", apiv1.ServicePort{Name: "http", Port: 8080})}},
+		{name: "route service not created yet", kind: "EnvoyRoute", obj: routeTo(intstr.FromInt(9090))},
+		{name: "route status written by the controller", kind: "EnvoyRoute", obj: routeStatus, old: routeTo(intstr.FromInt(9090)),
+			services: []runtime.Object{service("web", apiv1.ServicePort{Name: "http", Port: 8080})}},
+		{name: "listener port", kind: "EnvoyListener", obj: listenerTo(intstr.FromInt(5432)),
+			services: []runtime.Object{service("db", apiv1.ServicePort{Name: "postgres", Port: 5432})}},
+		{name: "listener port not exposed", kind: "EnvoyListener", obj: listenerTo(intstr.FromString("mysql")),
+			denied:   "spec.backend.port",
+			services: []runtime.Object{service("db", apiv1.ServicePort{Name: "postgres", Port: 5432})}},
+		{name: "tls route port not exposed", kind: "EnvoyListener", obj: tls,
+			denied:   "spec.tlsRoutes[0].backend.port",
+			services: []runtime.Object{service("db", apiv1.ServicePort{Name: "postgres", Port: 5432})}},
+		{name: "inline listener port not exposed", kind: "Envoy", denied: "spec.listeners[0].backend.port",
+			obj: validatedEnvoy("edge", func(e *v1.Envoy) {
+				e.Spec.Listeners = []v1.InlineListener{{Name: "db", Port: 5432, Protocol: v1.ListenerTCP, Backend: listenerTo(intstr.FromInt(3306)).Spec.Backend}}
+			}),
+			services: []runtime.Object{service("db", apiv1.ServicePort{Name: "postgres", Port: 5432})}},
+		{name: "not validated", kind: "Pod", obj: testPod(nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			for _, envoy := range tt.envoys {
+				if err := clientset.Tracker().Create(envoysResource, envoy, envoy.Namespace); err != nil {
+					t.Fatal(err)
+				}
+			}
+			validator := NewValidator(clientset, kubefake.NewSimpleClientset(tt.services...))
+
+			req := &v1beta1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Group: v1.SchemeGroupVersion.Group, Version: "v1", Kind: tt.kind},
+				Namespace: "shop",
+				Operation: v1beta1.Create,
+				Object:    raw(t, tt.obj),
+			}
+			if tt.old != nil {
+				req.Operation = v1beta1.Update
+				req.OldObject = raw(t, tt.old)
+			}
+			resp := send(t, validator.Handler(), req)
+			if tt.denied == "" {
+				if !resp.Allowed {
+					t.Fatalf("denied: %v", resp.Result.Message)
+				}
+				return
+			}
+			if resp.Allowed {
+				t.Fatalf("allowed, want a denial mentioning %q", tt.denied)
+			}
+			if !strings.Contains(resp.Result.Message, tt.denied) {
+				t.Fatalf("denied with %q, want it to mention %q", resp.Result.Message, tt.denied)
+			}
+		})
+	}
+}
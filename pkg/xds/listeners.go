@@ -0,0 +1,130 @@
+package xds
+
+import (
+	"fmt"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	router "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	tlsinspector "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/tls_inspector/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcpproxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	apiv1 "k8s.io/api/core/v1"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+)
+
+// ListenerResources translates envoy listeners into LDS listeners. A listener whose backends are
+// not among services is left out, and its error is returned by name.
+func ListenerResources(listeners []*v1.EnvoyListener, services []*apiv1.Service) ([]types.Resource, map[string]error) {
+	clusters := clusterNames(services)
+	rejected := map[string]error{}
+
+	var out []types.Resource
+	for _, l := range listeners {
+		lis, err := translateListener(l, clusters)
+		if err != nil {
+			rejected[l.Name] = err
+			continue
+		}
+		out = append(out, lis)
+	}
+	return out, rejected
+}
+
+func translateListener(l *v1.EnvoyListener, clusters map[string]string) (*listener.Listener, error) {
+	out := &listener.Listener{
+		Name:    fmt.Sprintf("%s/%s", l.Namespace, l.Name),
+		Address: socketAddress("0.0.0.0", uint32(l.Spec.Port)),
+	}
+	switch l.Spec.Protocol {
+	case v1.ListenerHTTP:
+		filter, err := typedFilter(wellknown.HTTPConnectionManager, httpConnectionManager(out.Name))
+		if err != nil {
+			return nil, err
+		}
+		out.FilterChains = []*listener.FilterChain{{Filters: []*listener.Filter{filter}}}
+	case v1.ListenerTCP:
+		if l.Spec.Backend == nil {
+			return nil, fmt.Errorf("TCP listeners need a backend")
+		}
+		chain, err := tcpProxyChain(out.Name, *l.Spec.Backend, clusters)
+		if err != nil {
+			return nil, err
+		}
+		out.FilterChains = []*listener.FilterChain{chain}
+	case v1.ListenerTLS:
+		if len(l.Spec.TLSRoutes) == 0 {
+			return nil, fmt.Errorf("TLS listeners need at least one tlsRoute")
+		}
+		inspector, err := anypb.New(&tlsinspector.TlsInspector{})
+		if err != nil {
+			return nil, err
+		}
+		out.ListenerFilters = []*listener.ListenerFilter{{
+			Name:       wellknown.TlsInspector,
+			ConfigType: &listener.ListenerFilter_TypedConfig{TypedConfig: inspector},
+		}}
+		for _, r := range l.Spec.TLSRoutes {
+			chain, err := tcpProxyChain(out.Name, r.Backend, clusters)
+			if err != nil {
+				return nil, err
+			}
+			chain.FilterChainMatch = &listener.FilterChainMatch{
+				ServerNames:       r.ServerNames,
+				TransportProtocol: "tls",
+			}
+			out.FilterChains = append(out.FilterChains, chain)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", l.Spec.Protocol)
+	}
+	return out, nil
+}
+
+func httpConnectionManager(statPrefix string) *hcm.HttpConnectionManager {
+	routerConfig, _ := anypb.New(&router.Router{})
+	return &hcm.HttpConnectionManager{
+		StatPrefix: statPrefix,
+		CodecType:  hcm.HttpConnectionManager_AUTO,
+		RouteSpecifier: &hcm.HttpConnectionManager_Rds{
+			Rds: &hcm.Rds{
+				ConfigSource:    adsConfigSource(),
+				RouteConfigName: RouteConfigName,
+			},
+		},
+		HttpFilters: []*hcm.HttpFilter{{
+			Name:       wellknown.Router,
+			ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: routerConfig},
+		}},
+	}
+}
+
+func tcpProxyChain(statPrefix string, backend v1.RouteBackend, clusters map[string]string) (*listener.FilterChain, error) {
+	cluster, err := backendCluster(backend, clusters)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := typedFilter(wellknown.TCPProxy, &tcpproxy.TcpProxy{
+		StatPrefix:       statPrefix,
+		ClusterSpecifier: &tcpproxy.TcpProxy_Cluster{Cluster: cluster},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &listener.FilterChain{Filters: []*listener.Filter{filter}}, nil
+}
+
+func typedFilter(name string, config proto.Message) (*listener.Filter, error) {
+	typed, err := anypb.New(config)
+	if err != nil {
+		return nil, err
+	}
+	return &listener.Filter{
+		Name:       name,
+		ConfigType: &listener.Filter_TypedConfig{TypedConfig: typed},
+	}, nil
+}
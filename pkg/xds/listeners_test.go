@@ -0,0 +1,128 @@
+package xds
+
+import (
+	"reflect"
+	"testing"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcpproxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+)
+
+func testListener(name string, spec v1.EnvoyListenerSpec) *v1.EnvoyListener {
+	return &v1.EnvoyListener{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: name}, Spec: spec}
+}
+
+// proxiedCluster decodes the cluster a tcp proxy filter chain forwards to
+func proxiedCluster(t *testing.T, chain *listener.FilterChain) string {
+	t.Helper()
+	proxy := &tcpproxy.TcpProxy{}
+	if err := chain.Filters[0].GetTypedConfig().UnmarshalTo(proxy); err != nil {
+		t.Fatal(err)
+	}
+	return proxy.GetCluster()
+}
+
+func TestListenerResources(t *testing.T) {
+	web := backend("web", intstr.FromString("http"), 0)
+	api := backend("api", intstr.FromInt(9090), 0)
+	tests := []struct {
+		name      string
+		listeners []*v1.EnvoyListener
+		// names are the LDS listeners served, in order
+		names []string
+		// rejected maps listener names to a substring of their error
+		rejected map[string]string
+		check    func(t *testing.T, out []*listener.Listener)
+	}{
+		{name: "http", listeners: []*v1.EnvoyListener{testListener("http", v1.EnvoyListenerSpec{Port: 80, Protocol: v1.ListenerHTTP})},
+			names: []string{"shop/http"},
+			check: func(t *testing.T, out []*listener.Listener) {
+				if port := out[0].Address.GetSocketAddress().GetPortValue(); port != 80 {
+					t.Fatalf("port %d, want 80", port)
+				}
+				manager := &hcm.HttpConnectionManager{}
+				filter := out[0].FilterChains[0].Filters[0]
+				if filter.Name != wellknown.HTTPConnectionManager {
+					t.Fatalf("filter %s, want %s", filter.Name, wellknown.HTTPConnectionManager)
+				}
+				if err := filter.GetTypedConfig().UnmarshalTo(manager); err != nil {
+					t.Fatal(err)
+				}
+				if name := manager.GetRds().GetRouteConfigName(); name != RouteConfigName {
+					t.Fatalf("route configuration %q, want %q", name, RouteConfigName)
+				}
+			}},
+		{name: "tcp", listeners: []*v1.EnvoyListener{testListener("db", v1.EnvoyListenerSpec{Port: 5432, Protocol: v1.ListenerTCP, Backend: &api})},
+			names: []string{"shop/db"},
+			check: func(t *testing.T, out []*listener.Listener) {
+				if cluster := proxiedCluster(t, out[0].FilterChains[0]); cluster != "shop/api/9090" {
+					t.Fatalf("cluster %q, want shop/api/9090", cluster)
+				}
+			}},
+		{name: "tcp without a backend", listeners: []*v1.EnvoyListener{testListener("db", v1.EnvoyListenerSpec{Port: 5432, Protocol: v1.ListenerTCP})},
+			rejected: map[string]string{"db": "TCP listeners need a backend"}},
+		{name: "tcp backend not exposed",
+			listeners: []*v1.EnvoyListener{testListener("db", v1.EnvoyListenerSpec{Port: 5432, Protocol: v1.ListenerTCP,
+				Backend: &v1.RouteBackend{Service: "db", Port: intstr.FromInt(5432)}})},
+			rejected: map[string]string{"db": "backend db:5432 is not exposed"}},
+		{name: "tls",
+			listeners: []*v1.EnvoyListener{testListener("tls", v1.EnvoyListenerSpec{Port: 443, Protocol: v1.ListenerTLS, TLSRoutes: []v1.TLSRoute{
+				{ServerNames: []string{"www.example"}, Backend: web},
+				{ServerNames: []string{"api.example"}, Backend: api},
+			}})},
+			names: []string{"shop/tls"},
+			check: func(t *testing.T, out []*listener.Listener) {
+				if len(out[0].ListenerFilters) != 1 || out[0].ListenerFilters[0].Name != wellknown.TlsInspector {
+					t.Fatalf("listener filters %v, want the tls inspector", out[0].ListenerFilters)
+				}
+				var clusters, serverNames []string
+				for _, chain := range out[0].FilterChains {
+					clusters = append(clusters, proxiedCluster(t, chain))
+					serverNames = append(serverNames, chain.FilterChainMatch.ServerNames...)
+				}
+				if !reflect.DeepEqual(clusters, []string{"shop/web/http", "shop/api/9090"}) {
+					t.Fatalf("clusters %v", clusters)
+				}
+				if !reflect.DeepEqual(serverNames, []string{"www.example", "api.example"}) {
+					t.Fatalf("server names %v", serverNames)
+				}
+			}},
+		{name: "tls without routes", listeners: []*v1.EnvoyListener{testListener("tls", v1.EnvoyListenerSpec{Port: 443, Protocol: v1.ListenerTLS})},
+			rejected: map[string]string{"tls": "at least one tlsRoute"}},
+		{name: "unsupported protocol", listeners: []*v1.EnvoyListener{testListener("udp", v1.EnvoyListenerSpec{Port: 53, Protocol: "UDP"})},
+			rejected: map[string]string{"udp": `unsupported protocol "UDP"`}},
+		{name: "rejected listeners leave the others",
+			listeners: []*v1.EnvoyListener{
+				testListener("http", v1.EnvoyListenerSpec{Port: 80, Protocol: v1.ListenerHTTP}),
+				testListener("db", v1.EnvoyListenerSpec{Port: 5432, Protocol: v1.ListenerTCP}),
+				testListener("api", v1.EnvoyListenerSpec{Port: 9090, Protocol: v1.ListenerTCP, Backend: &api}),
+			},
+			names:    []string{"shop/http", "shop/api"},
+			rejected: map[string]string{"db": "need a backend"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, rejected := ListenerResources(tt.listeners, testServices())
+			var out []*listener.Listener
+			var names []string
+			for _, r := range res {
+				l := r.(*listener.Listener)
+				out = append(out, l)
+				names = append(names, l.Name)
+			}
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Fatalf("listeners %v, want %v", names, tt.names)
+			}
+			checkRejected(t, rejected, tt.rejected)
+			if tt.check != nil {
+				tt.check(t, out)
+			}
+		})
+	}
+}
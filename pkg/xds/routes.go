@@ -0,0 +1,248 @@
+package xds
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	apiv1 "k8s.io/api/core/v1"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+)
+
+// RouteConfigName is the RDS route configuration holding every virtual host of a fleet
+const RouteConfigName = "default"
+
+// RouteResources compiles the envoy routes selecting a fleet into a single route configuration.
+// Routes are applied in order; a route whose backends are not among services, whose domains are
+// already claimed by an earlier route or repeated among its own virtual hosts, or whose virtual
+// host names repeat is left out, and its error is returned by name.
+func RouteResources(routes []*v1.EnvoyRoute, services []*apiv1.Service) (types.Resource, map[string]error) {
+	clusters := clusterNames(services)
+	claimed := map[string]string{}
+	rejected := map[string]error{}
+
+	config := &route.RouteConfiguration{Name: RouteConfigName}
+	for _, r := range routes {
+		vhosts, err := virtualHosts(r, clusters, claimed)
+		if err != nil {
+			rejected[r.Name] = err
+			continue
+		}
+		for _, vh := range vhosts {
+			for _, domain := range vh.Domains {
+				claimed[domain] = r.Name
+			}
+		}
+		config.VirtualHosts = append(config.VirtualHosts, vhosts...)
+	}
+	return config, rejected
+}
+
+// clusterNames indexes cluster names by "service/port" for both port names and numbers
+func clusterNames(services []*apiv1.Service) map[string]string {
+	names := map[string]string{}
+	for _, svc := range services {
+		for _, port := range svc.Spec.Ports {
+			name := ClusterName(svc.Namespace, svc.Name, port)
+			names[svc.Name+"/"+strconv.Itoa(int(port.Port))] = name
+			if port.Name != "" {
+				names[svc.Name+"/"+port.Name] = name
+			}
+		}
+	}
+	return names
+}
+
+func virtualHosts(r *v1.EnvoyRoute, clusters map[string]string, claimed map[string]string) ([]*route.VirtualHost, error) {
+	var vhosts []*route.VirtualHost
+	names := map[string]bool{}
+	domains := map[string]string{}
+	for _, vh := range r.Spec.VirtualHosts {
+		if names[vh.Name] {
+			return nil, fmt.Errorf("virtual host %s is defined twice", vh.Name)
+		}
+		names[vh.Name] = true
+		for _, domain := range vh.Domains {
+			if owner, ok := claimed[domain]; ok {
+				return nil, fmt.Errorf("domain %q is already served by route %s", domain, owner)
+			}
+			if other, ok := domains[domain]; ok {
+				return nil, fmt.Errorf("domain %q is served by both virtual hosts %s and %s", domain, other, vh.Name)
+			}
+			domains[domain] = vh.Name
+		}
+		vhost := &route.VirtualHost{
+			Name:    fmt.Sprintf("%s/%s/%s", r.Namespace, r.Name, vh.Name),
+			Domains: vh.Domains,
+		}
+		for i, rt := range vh.Routes {
+			out, err := translateRoute(rt, clusters)
+			if err != nil {
+				return nil, fmt.Errorf("virtual host %s route %d: %v", vh.Name, i, err)
+			}
+			vhost.Routes = append(vhost.Routes, out)
+		}
+		vhosts = append(vhosts, vhost)
+	}
+	return vhosts, nil
+}
+
+func translateRoute(rt v1.Route, clusters map[string]string) (*route.Route, error) {
+	out := &route.Route{Match: routeMatch(rt.Match)}
+	if rt.Redirect != nil {
+		redirect, err := redirectAction(rt.Redirect)
+		if err != nil {
+			return nil, err
+		}
+		out.Action = &route.Route_Redirect{Redirect: redirect}
+		return out, nil
+	}
+
+	action := &route.RouteAction{}
+	switch len(rt.Backends) {
+	case 0:
+		return nil, fmt.Errorf("either backends or redirect must be set")
+	case 1:
+		name, err := backendCluster(rt.Backends[0], clusters)
+		if err != nil {
+			return nil, err
+		}
+		action.ClusterSpecifier = &route.RouteAction_Cluster{Cluster: name}
+	default:
+		weights, err := backendWeights(rt.Backends)
+		if err != nil {
+			return nil, err
+		}
+		weighted := &route.WeightedCluster{}
+		for i, b := range rt.Backends {
+			name, err := backendCluster(b, clusters)
+			if err != nil {
+				return nil, err
+			}
+			weighted.Clusters = append(weighted.Clusters, &route.WeightedCluster_ClusterWeight{
+				Name:   name,
+				Weight: wrapperspb.UInt32(weights[i]),
+			})
+		}
+		action.ClusterSpecifier = &route.RouteAction_WeightedClusters{WeightedClusters: weighted}
+	}
+	if rt.Timeout != nil {
+		action.Timeout = durationpb.New(rt.Timeout.Duration)
+	}
+	if rt.Retries != nil {
+		action.RetryPolicy = retryPolicy(rt.Retries)
+	}
+	out.Action = &route.Route_Route{Route: action}
+	return out, nil
+}
+
+// backendWeights splits traffic evenly when no backend sets a weight, as envoy rejects the whole
+// route configuration over weights adding up to zero. Weights overflowing their sum are refused.
+func backendWeights(backends []v1.RouteBackend) ([]uint32, error) {
+	weights := make([]uint32, len(backends))
+	var total uint64
+	for i, b := range backends {
+		weights[i] = b.Weight
+		total += uint64(b.Weight)
+	}
+	if total > math.MaxUint32 {
+		return nil, fmt.Errorf("backend weights add up to %d, more than %d", total, uint32(math.MaxUint32))
+	}
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	return weights, nil
+}
+
+func backendCluster(b v1.RouteBackend, clusters map[string]string) (string, error) {
+	name, ok := clusters[b.Service+"/"+b.Port.String()]
+	if !ok {
+		return "", fmt.Errorf("backend %s:%s is not exposed to this envoy", b.Service, b.Port.String())
+	}
+	return name, nil
+}
+
+func routeMatch(m v1.RouteMatch) *route.RouteMatch {
+	out := &route.RouteMatch{}
+	switch {
+	case m.Path != "":
+		out.PathSpecifier = &route.RouteMatch_Path{Path: m.Path}
+	case m.Prefix != "":
+		out.PathSpecifier = &route.RouteMatch_Prefix{Prefix: m.Prefix}
+	default:
+		out.PathSpecifier = &route.RouteMatch_Prefix{Prefix: "/"}
+	}
+	for _, h := range m.Headers {
+		header := &route.HeaderMatcher{Name: h.Name}
+		if h.Value == "" {
+			header.HeaderMatchSpecifier = &route.HeaderMatcher_PresentMatch{PresentMatch: true}
+		} else {
+			header.HeaderMatchSpecifier = &route.HeaderMatcher_StringMatch{StringMatch: exact(h.Value)}
+		}
+		out.Headers = append(out.Headers, header)
+	}
+	for _, q := range m.QueryParams {
+		param := &route.QueryParameterMatcher{Name: q.Name}
+		if q.Value == "" {
+			param.QueryParameterMatchSpecifier = &route.QueryParameterMatcher_PresentMatch{PresentMatch: true}
+		} else {
+			param.QueryParameterMatchSpecifier = &route.QueryParameterMatcher_StringMatch{StringMatch: exact(q.Value)}
+		}
+		out.QueryParameters = append(out.QueryParameters, param)
+	}
+	return out
+}
+
+func exact(value string) *matcher.StringMatcher {
+	return &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Exact{Exact: value}}
+}
+
+var redirectCodes = map[int]route.RedirectAction_RedirectResponseCode{
+	0:   route.RedirectAction_MOVED_PERMANENTLY,
+	301: route.RedirectAction_MOVED_PERMANENTLY,
+	302: route.RedirectAction_FOUND,
+	303: route.RedirectAction_SEE_OTHER,
+	307: route.RedirectAction_TEMPORARY_REDIRECT,
+	308: route.RedirectAction_PERMANENT_REDIRECT,
+}
+
+func redirectAction(r *v1.RouteRedirect) (*route.RedirectAction, error) {
+	code, ok := redirectCodes[r.Code]
+	if !ok {
+		return nil, fmt.Errorf("unsupported redirect code %d", r.Code)
+	}
+	out := &route.RedirectAction{
+		HostRedirect: r.Host,
+		ResponseCode: code,
+	}
+	if r.Path != "" {
+		out.PathRewriteSpecifier = &route.RedirectAction_PathRedirect{PathRedirect: r.Path}
+	}
+	if r.HTTPS {
+		out.SchemeRewriteSpecifier = &route.RedirectAction_HttpsRedirect{HttpsRedirect: true}
+	}
+	return out, nil
+}
+
+func retryPolicy(r *v1.RouteRetries) *route.RetryPolicy {
+	on := r.On
+	if on == "" {
+		on = "5xx"
+	}
+	out := &route.RetryPolicy{
+		RetryOn:    on,
+		NumRetries: wrapperspb.UInt32(r.Attempts),
+	}
+	if r.PerTryTimeout != nil {
+		out.PerTryTimeout = durationpb.New(r.PerTryTimeout.Duration)
+	}
+	return out
+}
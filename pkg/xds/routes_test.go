@@ -0,0 +1,186 @@
+package xds
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+)
+
+// testServices expose web on a named port and api on a number only
+func testServices() []*apiv1.Service {
+	return []*apiv1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web"},
+			Spec:       apiv1.ServiceSpec{Ports: []apiv1.ServicePort{{Name: "http", Port: 8080}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "api"},
+			Spec:       apiv1.ServiceSpec{Ports: []apiv1.ServicePort{{Port: 9090}}},
+		},
+	}
+}
+
+func backend(service string, port intstr.IntOrString, weight uint32) v1.RouteBackend {
+	return v1.RouteBackend{Service: service, Port: port, Weight: weight}
+}
+
+func vhost(name string, domains []string, routes ...v1.Route) v1.VirtualHost {
+	return v1.VirtualHost{Name: name, Domains: domains, Routes: routes}
+}
+
+func routeTo(backends ...v1.RouteBackend) v1.Route {
+	return v1.Route{Backends: backends}
+}
+
+func testRoute(name string, vhosts ...v1.VirtualHost) *v1.EnvoyRoute {
+	return &v1.EnvoyRoute{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: name},
+		Spec:       v1.EnvoyRouteSpec{VirtualHosts: vhosts},
+	}
+}
+
+// weightsOf returns the weights of the first route of the first virtual host
+func weightsOf(config *route.RouteConfiguration) []uint32 {
+	var weights []uint32
+	for _, c := range config.VirtualHosts[0].Routes[0].GetRoute().GetWeightedClusters().GetClusters() {
+		weights = append(weights, c.Weight.GetValue())
+	}
+	return weights
+}
+
+func TestRouteResources(t *testing.T) {
+	web := backend("web", intstr.FromString("http"), 0)
+	api := backend("api", intstr.FromInt(9090), 0)
+	tests := []struct {
+		name   string
+		routes []*v1.EnvoyRoute
+		// vhosts are the names of the virtual hosts served, in order
+		vhosts []string
+		// rejected maps route names to a substring of their error
+		rejected map[string]string
+		check    func(t *testing.T, config *route.RouteConfiguration)
+	}{
+		{name: "single backend", routes: []*v1.EnvoyRoute{testRoute("web", vhost("www", []string{"www.example"}, routeTo(web)))},
+			vhosts: []string{"shop/web/www"},
+			check: func(t *testing.T, config *route.RouteConfiguration) {
+				if got := config.VirtualHosts[0].Routes[0].GetRoute().GetCluster(); got != "shop/web/http" {
+					t.Fatalf("cluster %q, want shop/web/http", got)
+				}
+			}},
+		{name: "weighted backends",
+			routes: []*v1.EnvoyRoute{testRoute("web", vhost("www", []string{"www.example"},
+				routeTo(backend("web", intstr.FromInt(8080), 90), backend("api", intstr.FromInt(9090), 10))))},
+			vhosts: []string{"shop/web/www"},
+			check: func(t *testing.T, config *route.RouteConfiguration) {
+				if got := weightsOf(config); !reflect.DeepEqual(got, []uint32{90, 10}) {
+					t.Fatalf("weights %v, want [90 10]", got)
+				}
+			}},
+		{name: "weights left out split evenly",
+			routes: []*v1.EnvoyRoute{testRoute("web", vhost("www", []string{"www.example"}, routeTo(web, api)))},
+			vhosts: []string{"shop/web/www"},
+			check: func(t *testing.T, config *route.RouteConfiguration) {
+				if got := weightsOf(config); !reflect.DeepEqual(got, []uint32{1, 1}) {
+					t.Fatalf("weights %v, want [1 1]", got)
+				}
+			}},
+		{name: "some weights left out",
+			routes: []*v1.EnvoyRoute{testRoute("web", vhost("www", []string{"www.example"}, routeTo(web, backend("api", intstr.FromInt(9090), 5))))},
+			vhosts: []string{"shop/web/www"},
+			check: func(t *testing.T, config *route.RouteConfiguration) {
+				if got := weightsOf(config); !reflect.DeepEqual(got, []uint32{0, 5}) {
+					t.Fatalf("weights %v, want [0 5]", got)
+				}
+			}},
+		{name: "weights overflow",
+			routes: []*v1.EnvoyRoute{testRoute("web", vhost("www", []string{"www.example"},
+				routeTo(backend("web", intstr.FromInt(8080), math.MaxUint32), backend("api", intstr.FromInt(9090), 1))))},
+			rejected: map[string]string{"web": "weights add up to 4294967296"}},
+		{name: "backend not exposed",
+			routes:   []*v1.EnvoyRoute{testRoute("web", vhost("www", []string{"www.example"}, routeTo(backend("web", intstr.FromInt(9090), 0))))},
+			rejected: map[string]string{"web": "backend web:9090 is not exposed"}},
+		{name: "redirect",
+			routes: []*v1.EnvoyRoute{testRoute("web", vhost("www", []string{"www.example"},
+				v1.Route{Redirect: &v1.RouteRedirect{Host: "example.com", Code: 302}}))},
+			vhosts: []string{"shop/web/www"},
+			check: func(t *testing.T, config *route.RouteConfiguration) {
+				redirect := config.VirtualHosts[0].Routes[0].GetRedirect()
+				if redirect.GetHostRedirect() != "example.com" || redirect.GetResponseCode() != route.RedirectAction_FOUND {
+					t.Fatalf("redirect %v, want a 302 to example.com", redirect)
+				}
+			}},
+		{name: "unsupported redirect code",
+			routes:   []*v1.EnvoyRoute{testRoute("web", vhost("www", []string{"www.example"}, v1.Route{Redirect: &v1.RouteRedirect{Code: 304}}))},
+			rejected: map[string]string{"web": "unsupported redirect code 304"}},
+		{name: "domain claimed by an earlier route",
+			routes: []*v1.EnvoyRoute{
+				testRoute("web", vhost("www", []string{"www.example"}, routeTo(web))),
+				testRoute("api", vhost("www", []string{"api.example", "www.example"}, routeTo(api))),
+				testRoute("docs", vhost("docs", []string{"docs.example"}, routeTo(web))),
+			},
+			vhosts:   []string{"shop/web/www", "shop/docs/docs"},
+			rejected: map[string]string{"api": `domain "www.example" is already served by route web`}},
+		{name: "domain repeated across the virtual hosts of a route",
+			routes: []*v1.EnvoyRoute{testRoute("web",
+				vhost("www", []string{"www.example"}, routeTo(web)),
+				vhost("api", []string{"api.example", "www.example"}, routeTo(api)))},
+			rejected: map[string]string{"web": `domain "www.example" is served by both virtual hosts www and api`}},
+		{name: "virtual host defined twice",
+			routes: []*v1.EnvoyRoute{testRoute("web",
+				vhost("www", []string{"www.example"}, routeTo(web)),
+				vhost("www", []string{"api.example"}, routeTo(api)))},
+			rejected: map[string]string{"web": "virtual host www is defined twice"}},
+		{name: "rejected route claims no domain",
+			routes: []*v1.EnvoyRoute{
+				testRoute("web", vhost("www", []string{"www.example"}, routeTo(backend("web", intstr.FromInt(9090), 0)))),
+				testRoute("api", vhost("www", []string{"www.example"}, routeTo(api))),
+			},
+			vhosts:   []string{"shop/api/www"},
+			rejected: map[string]string{"web": "not exposed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, rejected := RouteResources(tt.routes, testServices())
+			config := res.(*route.RouteConfiguration)
+			if config.Name != RouteConfigName {
+				t.Fatalf("route configuration %q, want %q", config.Name, RouteConfigName)
+			}
+			var vhosts []string
+			for _, vh := range config.VirtualHosts {
+				vhosts = append(vhosts, vh.Name)
+			}
+			if !reflect.DeepEqual(vhosts, tt.vhosts) {
+				t.Fatalf("virtual hosts %v, want %v", vhosts, tt.vhosts)
+			}
+			checkRejected(t, rejected, tt.rejected)
+			if tt.check != nil {
+				tt.check(t, config)
+			}
+		})
+	}
+}
+
+// checkRejected compares errors by name against substrings they should contain
+func checkRejected(t *testing.T, got map[string]error, want map[string]string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("rejected %v, want %v", got, want)
+	}
+	for name, substr := range want {
+		err, ok := got[name]
+		if !ok {
+			t.Fatalf("%s was not rejected, got %v", name, got)
+		}
+		if !strings.Contains(err.Error(), substr) {
+			t.Fatalf("%s rejected with %q, want it to mention %q", name, err, substr)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package xds
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	clusterservice "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	discoverygrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	endpointservice "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
+	listenerservice "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
+	routeservice "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cache "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	cplog "github.com/envoyproxy/go-control-plane/pkg/log"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	server "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// Resources is the set of xDS resources served to a single node
+type Resources struct {
+	Clusters  []types.Resource
+	Endpoints []types.Resource
+	Routes    []types.Resource
+	Listeners []types.Resource
+}
+
+func (r Resources) byType() map[resource.Type][]types.Resource {
+	return map[resource.Type][]types.Resource{
+		resource.ClusterType:  r.Clusters,
+		resource.EndpointType: r.Endpoints,
+		resource.RouteType:    r.Routes,
+		resource.ListenerType: r.Listeners,
+	}
+}
+
+// Server is an ADS management server backed by a snapshot cache keyed by node ID
+type Server struct {
+	cache cache.SnapshotCache
+	// OnConnectionChange, if set, is called with the node ID whenever a proxy of that node connects or
+	// disconnects; not for injected sidecars
+	OnConnectionChange func(nodeID string)
+
+	mu        sync.Mutex
+	version   uint64
+	resources map[string]Resources
+	versions  map[string]uint64
+
+	streamsMu sync.Mutex
+	streams   map[int64]string
+	connected map[string]int
+}
+
+// stopTimeout is how long Run waits for streams to finish once stopped before closing them
+var stopTimeout = 5 * time.Second
+
+// logger hands the snapshot cache's messages to slog, its per request chatter only at debug
+var logger = cplog.LoggerFuncs{
+	DebugFunc: func(format string, args ...interface{}) { slog.Debug(fmt.Sprintf(format, args...)) },
+	InfoFunc:  func(format string, args ...interface{}) { slog.Debug(fmt.Sprintf(format, args...)) },
+	WarnFunc:  func(format string, args ...interface{}) { slog.Warn(fmt.Sprintf(format, args...)) },
+	ErrorFunc: func(format string, args ...interface{}) { slog.Error(fmt.Sprintf(format, args...)) },
+}
+
+// NewServer returns an xds server with an empty snapshot cache
+func NewServer() *Server {
+	return &Server{
+		cache:     cache.NewSnapshotCache(true, nodeHash{}, logger),
+		resources: map[string]Resources{},
+		versions:  map[string]uint64{},
+		streams:   map[int64]string{},
+		connected: map[string]int{},
+	}
+}
+
+// Register adds the aggregated and per-type discovery services to a grpc server
+func (s *Server) Register(grpcServer *grpc.Server) {
+	xdsServer := server.NewServer(context.Background(), s.cache, s.callbacks())
+	discoverygrpc.RegisterAggregatedDiscoveryServiceServer(grpcServer, xdsServer)
+	endpointservice.RegisterEndpointDiscoveryServiceServer(grpcServer, xdsServer)
+	clusterservice.RegisterClusterDiscoveryServiceServer(grpcServer, xdsServer)
+	routeservice.RegisterRouteDiscoveryServiceServer(grpcServer, xdsServer)
+	listenerservice.RegisterListenerDiscoveryServiceServer(grpcServer, xdsServer)
+}
+
+// Run serves xds on addr until stopCh is closed
+func (s *Server) Run(addr string, stopCh <-chan struct{}) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	grpcServer := grpc.NewServer()
+	s.Register(grpcServer)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-stopCh
+		// ads streams stay open as long as envoy runs, so graceful stop only gets a moment
+		graceful := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(graceful)
+		}()
+		select {
+		case <-graceful:
+		case <-time.After(stopTimeout):
+			grpcServer.Stop()
+		}
+	}()
+	slog.Info("XDS server listening", "address", addr)
+	if err := grpcServer.Serve(lis); err != nil {
+		return err
+	}
+	<-stopped
+	return nil
+}
+
+// SetResources publishes a new snapshot for nodeID, unless res is identical to what the node already has
+func (s *Server) SetResources(nodeID string, res Resources) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if prev, ok := s.resources[nodeID]; ok && equal(prev, res) {
+		return nil
+	}
+	s.version++
+	snapshot, err := cache.NewSnapshot(strconv.FormatUint(s.version, 10), res.byType())
+	if err != nil {
+		return err
+	}
+	if err := snapshot.Consistent(); err != nil {
+		return err
+	}
+	if err := s.cache.SetSnapshot(context.Background(), nodeID, snapshot); err != nil {
+		return err
+	}
+	s.resources[nodeID] = res
+	s.versions[nodeID] = s.version
+	return nil
+}
+
+// ClearResources drops the snapshot for nodeID
+func (s *Server) ClearResources(nodeID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cache.ClearSnapshot(nodeID)
+	delete(s.resources, nodeID)
+	delete(s.versions, nodeID)
+}
+
+// Versions returns the version of the snapshot served to each node
+func (s *Server) Versions() map[string]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := make(map[string]uint64, len(s.versions))
+	for nodeID, version := range s.versions {
+		versions[nodeID] = version
+	}
+	return versions
+}
+
+// Connected returns the number of open xds streams from proxies of nodeID
+func (s *Server) Connected(nodeID string) int {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+
+	return s.connected[nodeID]
+}
+
+// ConnectedNodes returns the number of open xds streams per node with any, injected sidecars aside
+func (s *Server) ConnectedNodes() map[string]int {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+
+	connected := map[string]int{}
+	for nodeID, n := range s.connected {
+		if n > 0 && nodeID != SidecarNodeID {
+			connected[nodeID] = n
+		}
+	}
+	return connected
+}
+
+// callbacks count streams per node, injected sidecars under SidecarNodeID; the node is only known
+// from the first request on a stream
+func (s *Server) callbacks() server.Callbacks {
+	return server.CallbackFuncs{
+		StreamRequestFunc: func(streamID int64, req *discoverygrpc.DiscoveryRequest) error {
+			if req.GetNode().GetId() == "" {
+				return nil
+			}
+			nodeID := nodeHash{}.ID(req.Node)
+			s.streamsMu.Lock()
+			_, known := s.streams[streamID]
+			if !known {
+				s.streams[streamID] = nodeID
+				s.connected[nodeID]++
+			}
+			s.streamsMu.Unlock()
+			if !known {
+				s.connectionChanged(nodeID)
+			}
+			return nil
+		},
+		StreamClosedFunc: func(streamID int64, _ *core.Node) {
+			s.streamsMu.Lock()
+			nodeID, known := s.streams[streamID]
+			if known {
+				delete(s.streams, streamID)
+				if s.connected[nodeID]--; s.connected[nodeID] == 0 {
+					delete(s.connected, nodeID)
+				}
+			}
+			s.streamsMu.Unlock()
+			if known {
+				s.connectionChanged(nodeID)
+			}
+		},
+	}
+}
+
+func (s *Server) connectionChanged(nodeID string) {
+	// sidecars belong to no envoy whose status would change
+	if s.OnConnectionChange != nil && nodeID != SidecarNodeID {
+		s.OnConnectionChange(nodeID)
+	}
+}
+
+func equal(a, b Resources) bool {
+	x, y := a.byType(), b.byType()
+	for typ := range x {
+		if len(x[typ]) != len(y[typ]) {
+			return false
+		}
+		for i := range x[typ] {
+			if !proto.Equal(x[typ][i], y[typ][i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
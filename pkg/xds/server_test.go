@@ -0,0 +1,263 @@
+package xds
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoverygrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testNode = "default/edge"
+
+// fakeEnvoy is an ADS client standing in for a proxy of testNode
+type fakeEnvoy struct {
+	t         *testing.T
+	stream    discoverygrpc.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+	responses chan *discoverygrpc.DiscoveryResponse
+}
+
+// startServer serves s over an in-memory listener and connects a fake envoy to it
+func startServer(t *testing.T, s *Server) *fakeEnvoy {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	s.Register(grpcServer)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	stream, err := discoverygrpc.NewAggregatedDiscoveryServiceClient(conn).StreamAggregatedResources(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envoy := &fakeEnvoy{t: t, stream: stream, responses: make(chan *discoverygrpc.DiscoveryResponse, 10)}
+	go func() {
+		defer close(envoy.responses)
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			envoy.responses <- resp
+		}
+	}()
+	return envoy
+}
+
+func (e *fakeEnvoy) send(req *discoverygrpc.DiscoveryRequest) {
+	e.t.Helper()
+	req.Node = &core.Node{Id: testNode, Cluster: "edge"}
+	if err := e.stream.Send(req); err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+// subscribe asks for clusters, the only wildcard type requested first
+func (e *fakeEnvoy) subscribe() {
+	e.send(&discoverygrpc.DiscoveryRequest{TypeUrl: resource.ClusterType})
+}
+
+func (e *fakeEnvoy) ack(resp *discoverygrpc.DiscoveryResponse) {
+	e.send(&discoverygrpc.DiscoveryRequest{TypeUrl: resp.TypeUrl, VersionInfo: resp.VersionInfo, ResponseNonce: resp.Nonce})
+}
+
+func (e *fakeEnvoy) nack(resp *discoverygrpc.DiscoveryResponse, version string) {
+	e.send(&discoverygrpc.DiscoveryRequest{
+		TypeUrl:       resp.TypeUrl,
+		VersionInfo:   version,
+		ResponseNonce: resp.Nonce,
+		ErrorDetail:   &status.Status{Message: "rejected"},
+	})
+}
+
+func (e *fakeEnvoy) recv() *discoverygrpc.DiscoveryResponse {
+	e.t.Helper()
+	select {
+	case resp, ok := <-e.responses:
+		if !ok {
+			e.t.Fatal("stream closed")
+		}
+		return resp
+	case <-time.After(5 * time.Second):
+		e.t.Fatal("no discovery response")
+		return nil
+	}
+}
+
+// expectNothing fails if a response arrives within a short while
+func (e *fakeEnvoy) expectNothing() {
+	e.t.Helper()
+	select {
+	case resp := <-e.responses:
+		e.t.Fatalf("unexpected response version %s", resp.VersionInfo)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// clusterNamesOf decodes the clusters of a CDS response
+func clusterNamesOf(t *testing.T, resp *discoverygrpc.DiscoveryResponse) []string {
+	t.Helper()
+	var names []string
+	for _, any := range resp.Resources {
+		c := &cluster.Cluster{}
+		if err := any.UnmarshalTo(c); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func serviceResources(ports ...int32) Resources {
+	svc := &apiv1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "api"}}
+	for _, p := range ports {
+		svc.Spec.Ports = append(svc.Spec.Ports, apiv1.ServicePort{Port: p})
+	}
+	return ServiceResources([]*apiv1.Service{svc}, nil)
+}
+
+func TestServerServesSnapshots(t *testing.T) {
+	s := NewServer()
+	changes := make(chan string, 10)
+	s.OnConnectionChange = func(nodeID string) { changes <- nodeID }
+	envoy := startServer(t, s)
+
+	if err := s.SetResources(testNode, serviceResources(80)); err != nil {
+		t.Fatal(err)
+	}
+	envoy.subscribe()
+	resp := envoy.recv()
+	if resp.TypeUrl != resource.ClusterType || resp.VersionInfo != "1" {
+		t.Fatalf("got %s version %s, want clusters version 1", resp.TypeUrl, resp.VersionInfo)
+	}
+	if names := clusterNamesOf(t, resp); len(names) != 1 || names[0] != "default/api/80" {
+		t.Fatalf("got clusters %v, want [default/api/80]", names)
+	}
+	if got := <-changes; got != testNode {
+		t.Fatalf("connection change for %q, want %q", got, testNode)
+	}
+	if n := s.Connected(testNode); n != 1 {
+		t.Fatalf("Connected = %d, want 1", n)
+	}
+	if nodes := s.ConnectedNodes(); len(nodes) != 1 || nodes[testNode] != 1 {
+		t.Fatalf("ConnectedNodes = %v, want %s: 1", nodes, testNode)
+	}
+	envoy.ack(resp)
+
+	// identical resources publish no new version
+	if err := s.SetResources(testNode, serviceResources(80)); err != nil {
+		t.Fatal(err)
+	}
+	envoy.expectNothing()
+
+	if err := s.SetResources(testNode, serviceResources(80, 443)); err != nil {
+		t.Fatal(err)
+	}
+	resp = envoy.recv()
+	if resp.VersionInfo != "2" {
+		t.Fatalf("got version %s, want 2", resp.VersionInfo)
+	}
+	if names := clusterNamesOf(t, resp); len(names) != 2 {
+		t.Fatalf("got clusters %v, want 2", names)
+	}
+	if got := s.Versions()[testNode]; got != 2 {
+		t.Fatalf("Versions = %d, want 2", got)
+	}
+}
+
+func TestServerAfterNack(t *testing.T) {
+	s := NewServer()
+	envoy := startServer(t, s)
+
+	if err := s.SetResources(testNode, serviceResources(80)); err != nil {
+		t.Fatal(err)
+	}
+	envoy.subscribe()
+	first := envoy.recv()
+	envoy.ack(first)
+
+	if err := s.SetResources(testNode, serviceResources(81)); err != nil {
+		t.Fatal(err)
+	}
+	rejected := envoy.recv()
+	if err := s.SetResources(testNode, serviceResources(82)); err != nil {
+		t.Fatal(err)
+	}
+	// nothing is pushed while the proxy has not answered the previous response
+	envoy.expectNothing()
+
+	// a proxy rejecting a version keeps running the last one it accepted and reports it, the server
+	// answers with the current snapshot
+	envoy.nack(rejected, first.VersionInfo)
+	resp := envoy.recv()
+	if resp.VersionInfo != "3" {
+		t.Fatalf("got version %s after the nack, want 3", resp.VersionInfo)
+	}
+	if names := clusterNamesOf(t, resp); len(names) != 1 || names[0] != "default/api/82" {
+		t.Fatalf("got clusters %v, want [default/api/82]", names)
+	}
+	envoy.ack(resp)
+	envoy.expectNothing()
+}
+
+func TestServerClosedStream(t *testing.T) {
+	s := NewServer()
+	changes := make(chan string, 10)
+	s.OnConnectionChange = func(nodeID string) { changes <- nodeID }
+	envoy := startServer(t, s)
+
+	if err := s.SetResources(testNode, serviceResources(80)); err != nil {
+		t.Fatal(err)
+	}
+	envoy.subscribe()
+	envoy.recv()
+	<-changes
+
+	if err := envoy.stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-changes:
+		if got != testNode {
+			t.Fatalf("connection change for %q, want %q", got, testNode)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("closing the stream reported no connection change")
+	}
+	if n := s.Connected(testNode); n != 0 {
+		t.Fatalf("Connected = %d after the stream closed, want 0", n)
+	}
+}
+
+func TestSetResourcesInconsistent(t *testing.T) {
+	s := NewServer()
+	res := serviceResources(80)
+	// an EDS cluster without its load assignment
+	res.Endpoints = nil
+	if err := s.SetResources(testNode, res); err == nil {
+		t.Fatal("inconsistent snapshot was accepted")
+	}
+	if _, ok := s.Versions()[testNode]; ok {
+		t.Fatal("inconsistent snapshot was published")
+	}
+}
@@ -0,0 +1,121 @@
+package xds
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"google.golang.org/protobuf/types/known/durationpb"
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// ClusterName returns the envoy cluster name for a service port
+func ClusterName(namespace, service string, port apiv1.ServicePort) string {
+	if port.Name != "" {
+		return fmt.Sprintf("%s/%s/%s", namespace, service, port.Name)
+	}
+	return fmt.Sprintf("%s/%s/%d", namespace, service, port.Port)
+}
+
+// ServiceResources turns services into EDS clusters and their endpoints into load assignments.
+// endpoints is looked up by service name; services without endpoints get an empty assignment.
+func ServiceResources(services []*apiv1.Service, endpoints map[string]*apiv1.Endpoints) Resources {
+	sorted := make([]*apiv1.Service, len(services))
+	copy(sorted, services)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Namespace+"/"+sorted[i].Name < sorted[j].Namespace+"/"+sorted[j].Name
+	})
+
+	res := Resources{}
+	for _, svc := range sorted {
+		for _, port := range svc.Spec.Ports {
+			if port.Protocol != "" && port.Protocol != apiv1.ProtocolTCP {
+				continue
+			}
+			name := ClusterName(svc.Namespace, svc.Name, port)
+			res.Clusters = append(res.Clusters, edsCluster(name))
+			res.Endpoints = append(res.Endpoints, loadAssignment(name, port, endpoints[svc.Name]))
+		}
+	}
+	return res
+}
+
+func edsCluster(name string) types.Resource {
+	return &cluster.Cluster{
+		Name:                 name,
+		ConnectTimeout:       durationpb.New(5 * time.Second),
+		ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
+		EdsClusterConfig: &cluster.Cluster_EdsClusterConfig{
+			EdsConfig: adsConfigSource(),
+		},
+	}
+}
+
+func loadAssignment(name string, port apiv1.ServicePort, eps *apiv1.Endpoints) types.Resource {
+	var lbEndpoints []*endpoint.LbEndpoint
+	if eps != nil {
+		for _, subset := range eps.Subsets {
+			targetPort, ok := endpointPort(subset, port)
+			if !ok {
+				continue
+			}
+			for _, addr := range subset.Addresses {
+				lbEndpoints = append(lbEndpoints, lbEndpoint(addr.IP, targetPort))
+			}
+		}
+	}
+	sort.Slice(lbEndpoints, func(i, j int) bool {
+		return lbEndpoints[i].String() < lbEndpoints[j].String()
+	})
+	return &endpoint.ClusterLoadAssignment{
+		ClusterName: name,
+		Endpoints: []*endpoint.LocalityLbEndpoints{{
+			LbEndpoints: lbEndpoints,
+		}},
+	}
+}
+
+// endpointPort finds the subset port backing a service port; endpoints reuse service port names
+func endpointPort(subset apiv1.EndpointSubset, port apiv1.ServicePort) (int32, bool) {
+	for _, p := range subset.Ports {
+		if p.Name == port.Name {
+			return p.Port, true
+		}
+	}
+	return 0, false
+}
+
+func lbEndpoint(ip string, port int32) *endpoint.LbEndpoint {
+	return &endpoint.LbEndpoint{
+		HostIdentifier: &endpoint.LbEndpoint_Endpoint{
+			Endpoint: &endpoint.Endpoint{
+				Address: socketAddress(ip, uint32(port)),
+			},
+		},
+	}
+}
+
+func socketAddress(host string, port uint32) *core.Address {
+	return &core.Address{
+		Address: &core.Address_SocketAddress{
+			SocketAddress: &core.SocketAddress{
+				Protocol: core.SocketAddress_TCP,
+				Address:  host,
+				PortSpecifier: &core.SocketAddress_PortValue{
+					PortValue: port,
+				},
+			},
+		},
+	}
+}
+
+func adsConfigSource() *core.ConfigSource {
+	return &core.ConfigSource{
+		ResourceApiVersion:    core.ApiVersion_V3,
+		ConfigSourceSpecifier: &core.ConfigSource_Ads{Ads: &core.AggregatedConfigSource{}},
+	}
+}
@@ -0,0 +1,99 @@
+package xds
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func endpoints(name string, subsets ...apiv1.EndpointSubset) *apiv1.Endpoints {
+	return &apiv1.Endpoints{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: name}, Subsets: subsets}
+}
+
+func subset(port apiv1.EndpointPort, ips ...string) apiv1.EndpointSubset {
+	s := apiv1.EndpointSubset{Ports: []apiv1.EndpointPort{port}}
+	for _, ip := range ips {
+		s.Addresses = append(s.Addresses, apiv1.EndpointAddress{IP: ip})
+	}
+	return s
+}
+
+// addressesOf lists the "ip:port" endpoints of every load assignment by cluster name
+func addressesOf(res Resources) map[string][]string {
+	out := map[string][]string{}
+	for _, r := range res.Endpoints {
+		assignment := r.(*endpoint.ClusterLoadAssignment)
+		addresses := []string{}
+		for _, locality := range assignment.Endpoints {
+			for _, lb := range locality.LbEndpoints {
+				addr := lb.GetEndpoint().GetAddress().GetSocketAddress()
+				addresses = append(addresses, fmt.Sprintf("%s:%d", addr.GetAddress(), addr.GetPortValue()))
+			}
+		}
+		out[assignment.ClusterName] = addresses
+	}
+	return out
+}
+
+func TestServiceResources(t *testing.T) {
+	web := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web"},
+		Spec: apiv1.ServiceSpec{Ports: []apiv1.ServicePort{
+			{Name: "http", Port: 80},
+			{Name: "dns", Port: 53, Protocol: apiv1.ProtocolUDP},
+		}},
+	}
+	api := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "api"},
+		Spec:       apiv1.ServiceSpec{Ports: []apiv1.ServicePort{{Port: 9090, Protocol: apiv1.ProtocolTCP}}},
+	}
+	tests := []struct {
+		name      string
+		services  []*apiv1.Service
+		endpoints map[string]*apiv1.Endpoints
+		// clusters are the EDS clusters served, in order
+		clusters  []string
+		addresses map[string][]string
+	}{
+		{name: "no services", addresses: map[string][]string{}},
+		{name: "sorted by namespace and name, udp ports left out", services: []*apiv1.Service{web, api},
+			clusters:  []string{"shop/api/9090", "shop/web/http"},
+			addresses: map[string][]string{"shop/api/9090": {}, "shop/web/http": {}}},
+		{name: "endpoints on the target port", services: []*apiv1.Service{web},
+			endpoints: map[string]*apiv1.Endpoints{"web": endpoints("web",
+				subset(apiv1.EndpointPort{Name: "http", Port: 8080}, "10.0.0.2", "10.0.0.1"),
+				subset(apiv1.EndpointPort{Name: "http", Port: 8081}, "10.0.0.3"),
+				subset(apiv1.EndpointPort{Name: "metrics", Port: 9100}, "10.0.0.4"),
+			)},
+			clusters:  []string{"shop/web/http"},
+			addresses: map[string][]string{"shop/web/http": {"10.0.0.1:8080", "10.0.0.2:8080", "10.0.0.3:8081"}}},
+		{name: "unnamed port", services: []*apiv1.Service{api},
+			endpoints: map[string]*apiv1.Endpoints{"api": endpoints("api", subset(apiv1.EndpointPort{Port: 9091}, "10.0.0.5"))},
+			clusters:  []string{"shop/api/9090"},
+			addresses: map[string][]string{"shop/api/9090": {"10.0.0.5:9091"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := ServiceResources(tt.services, tt.endpoints)
+			var clusters []string
+			for _, r := range res.Clusters {
+				c := r.(*cluster.Cluster)
+				if c.GetType() != cluster.Cluster_EDS {
+					t.Fatalf("cluster %s is %v, want EDS", c.Name, c.GetType())
+				}
+				clusters = append(clusters, c.Name)
+			}
+			if !reflect.DeepEqual(clusters, tt.clusters) {
+				t.Fatalf("clusters %v, want %v", clusters, tt.clusters)
+			}
+			if got := addressesOf(res); !reflect.DeepEqual(got, tt.addresses) {
+				t.Fatalf("endpoints %v, want %v", got, tt.addresses)
+			}
+		})
+	}
+}
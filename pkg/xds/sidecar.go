@@ -0,0 +1,92 @@
+package xds
+
+import (
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	originaldst "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/original_dst/v3"
+	tcpproxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// SidecarNodeID is the snapshot served to every injected sidecar, whatever node id it runs with
+	SidecarNodeID = "sidecar"
+	// SidecarMetadataKey set to "true" in the node metadata of a proxy marks it as an injected sidecar
+	SidecarMetadataKey = "envoy.starizard.io/sidecar"
+
+	// SidecarInboundPort and SidecarOutboundPort are where the init container of an injected pod
+	// redirects the pod's inbound and outbound tcp traffic
+	SidecarInboundPort  = 15006
+	SidecarOutboundPort = 15001
+
+	// PassthroughCluster forwards a connection to the address it was originally sent to
+	PassthroughCluster = "passthrough"
+)
+
+// SidecarResources returns what injected sidecars are served: a listener on each redirect port
+// passing connections on to their original destination, so that the redirected traffic of a pod
+// flows as it would without the sidecar
+func SidecarResources() (Resources, error) {
+	inbound, err := passthroughListener("sidecar/inbound", SidecarInboundPort)
+	if err != nil {
+		return Resources{}, err
+	}
+	outbound, err := passthroughListener("sidecar/outbound", SidecarOutboundPort)
+	if err != nil {
+		return Resources{}, err
+	}
+	return Resources{
+		Clusters: []types.Resource{&cluster.Cluster{
+			Name:                 PassthroughCluster,
+			ConnectTimeout:       durationpb.New(5 * time.Second),
+			ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_ORIGINAL_DST},
+			LbPolicy:             cluster.Cluster_CLUSTER_PROVIDED,
+		}},
+		Listeners: []types.Resource{inbound, outbound},
+	}, nil
+}
+
+// passthroughListener accepts redirected connections on port and proxies them to the destination
+// iptables rewrote, which the original_dst listener filter restores
+func passthroughListener(name string, port uint32) (*listener.Listener, error) {
+	originalDst, err := anypb.New(&originaldst.OriginalDst{})
+	if err != nil {
+		return nil, err
+	}
+	filter, err := typedFilter(wellknown.TCPProxy, &tcpproxy.TcpProxy{
+		StatPrefix:       name,
+		ClusterSpecifier: &tcpproxy.TcpProxy_Cluster{Cluster: PassthroughCluster},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &listener.Listener{
+		Name:    name,
+		Address: socketAddress("0.0.0.0", port),
+		ListenerFilters: []*listener.ListenerFilter{{
+			Name:       wellknown.OriginalDestination,
+			ConfigType: &listener.ListenerFilter_TypedConfig{TypedConfig: originalDst},
+		}},
+		FilterChains: []*listener.FilterChain{{Filters: []*listener.Filter{filter}}},
+	}, nil
+}
+
+// nodeHash keys the snapshot cache by node id, except for injected sidecars which all share SidecarNodeID
+type nodeHash struct{}
+
+func (nodeHash) ID(node *core.Node) string {
+	if isSidecar(node) {
+		return SidecarNodeID
+	}
+	return node.GetId()
+}
+
+func isSidecar(node *core.Node) bool {
+	return node.GetMetadata().GetFields()[SidecarMetadataKey].GetStringValue() == "true"
+}
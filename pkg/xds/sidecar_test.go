@@ -0,0 +1,62 @@
+package xds
+
+import (
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoverygrpc "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestSidecarResources(t *testing.T) {
+	res, err := SidecarResources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer()
+	if err := s.SetResources(SidecarNodeID, res); err != nil {
+		t.Fatalf("sidecar snapshot is inconsistent: %v", err)
+	}
+}
+
+func TestSidecarsShareASnapshot(t *testing.T) {
+	res, err := SidecarResources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer()
+	if err := s.SetResources(SidecarNodeID, res); err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan string, 10)
+	s.OnConnectionChange = func(nodeID string) { changes <- nodeID }
+	envoy := startServer(t, s)
+
+	metadata, err := structpb.NewStruct(map[string]interface{}{SidecarMetadataKey: "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &core.Node{Id: "shop/web-1", Cluster: "web", Metadata: metadata}
+	if got := (nodeHash{}).ID(req); got != SidecarNodeID {
+		t.Fatalf("sidecar hashed to %q, want %q", got, SidecarNodeID)
+	}
+	if got := (nodeHash{}).ID(&core.Node{Id: testNode}); got != testNode {
+		t.Fatalf("node hashed to %q, want %q", got, testNode)
+	}
+
+	if err := envoy.stream.Send(&discoverygrpc.DiscoveryRequest{TypeUrl: resource.ClusterType, Node: req}); err != nil {
+		t.Fatal(err)
+	}
+	if names := clusterNamesOf(t, envoy.recv()); len(names) != 1 || names[0] != PassthroughCluster {
+		t.Fatalf("got clusters %v, want [%s]", names, PassthroughCluster)
+	}
+	if n := s.Connected(SidecarNodeID); n != 1 {
+		t.Fatalf("Connected = %d, want 1", n)
+	}
+	select {
+	case nodeID := <-changes:
+		t.Fatalf("sidecar connection reported for %q", nodeID)
+	default:
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/starizard/kube-envoy-controller/pkg/healthz"
+)
+
+var (
+	// probes serves /healthz and /readyz on healthAddress, subsystems add their checks to it
+	probes        = healthz.NewServer()
+	healthAddress = ":8081"
+)
+
+// runProbes registers the controller's own checks and serves the probes. It runs before the caches
+// sync so that a slow start fails readiness rather than liveness.
+func runProbes(c *controller, synced []cache.InformerSynced) {
+	probes.Liveness.Add("workers", c.checkWorkers)
+	probes.Readiness.Add("informers", func() error {
+		for _, hasSynced := range synced {
+			if !hasSynced() {
+				return fmt.Errorf("caches not synced")
+			}
+		}
+		return nil
+	})
+	probes.Readiness.Add("leader", func() error {
+		if c.elector != nil && c.elector.Leader() == "" {
+			return fmt.Errorf("leader of the lease not known yet")
+		}
+		return nil
+	})
+
+	if healthAddress == "" {
+		slog.Info("Probes disabled")
+		return
+	}
+	serve("probe", func() error { return probes.Run(healthAddress, stopCh) })
+}
+
+// busy marks worker as syncing a key until the returned func is called
+func (c *controller) busy(worker int) func() {
+	atomic.StoreInt64(&c.busySince[worker], time.Now().UnixNano())
+	return func() { atomic.StoreInt64(&c.busySince[worker], 0) }
+}
+
+// checkWorkers fails when a worker has been stuck on one key for longer than workerTimeout, e.g. on
+// an api call that never returns; such a worker takes no further keys until the process restarts
+func (c *controller) checkWorkers() error {
+	for worker := range c.busySince {
+		since := atomic.LoadInt64(&c.busySince[worker])
+		if since == 0 {
+			continue
+		}
+		if busyFor := time.Since(time.Unix(0, since)); busyFor > c.workerTimeout {
+			return fmt.Errorf("worker %d has been syncing one envoy for %v", worker, busyFor.Round(time.Second))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// shutdownTimeout bounds how long in-flight reconciles and open server connections may delay exiting
+	shutdownTimeout = 25 * time.Second
+	// servers tracks the xds, webhook and metrics servers until they have closed
+	servers sync.WaitGroup
+)
+
+// signalContext returns a context cancelled on SIGINT or SIGTERM, cancelling it also closes stopCh.
+// A second signal exits at once.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		slog.Info("Shutting down", "signal", sig)
+		cancel()
+		<-signals
+		slog.Warn("Received second signal, exiting")
+		os.Exit(1)
+	}()
+	go func() {
+		<-ctx.Done()
+		close(stopCh)
+	}()
+	return ctx, cancel
+}
+
+// serve runs a server in the background and keeps track of it for shutdown
+func serve(name string, run func() error) {
+	servers.Add(1)
+	go func() {
+		defer servers.Done()
+		if err := run(); err != nil {
+			slog.Error("Server stopped", "server", name, "err", err)
+		}
+	}()
+}
+
+// shutdown stops the workers from picking up new keys and waits for the reconciles in flight and the
+// servers to finish. It returns the exit code: 1 if they did not finish within shutdownTimeout.
+func shutdown(c *controller, workersDone <-chan struct{}) int {
+	c.queue.ShutDown()
+	serversDone := make(chan struct{})
+	go func() {
+		servers.Wait()
+		close(serversDone)
+	}()
+
+	deadline := time.After(shutdownTimeout)
+	for workersDone != nil || serversDone != nil {
+		select {
+		case <-workersDone:
+			slog.Info("Workers stopped")
+			workersDone = nil
+		case <-serversDone:
+			slog.Info("Servers stopped")
+			serversDone = nil
+		case <-deadline:
+			slog.Error("Shutdown did not finish in time", "timeout", shutdownTimeout)
+			return 1
+		}
+	}
+	return 0
+}
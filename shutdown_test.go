@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/starizard/kube-envoy-controller/pkg/client/clientset/versioned/fake"
+	"github.com/starizard/kube-envoy-controller/pkg/config"
+	"github.com/starizard/kube-envoy-controller/pkg/leader"
+)
+
+// electedController returns a controller campaigning for a lease in kubeclientset
+func electedController(kubeclientset *kubefake.Clientset) *controller {
+	c := newController(config.Default(), fake.NewSimpleClientset(), kubeclientset, record.NewFakeRecorder(10))
+	c.elector = leader.NewElector(kubeclientset, leader.Config{
+		Name:          "kube-envoy-controller",
+		Namespace:     "default",
+		Identity:      "replica-a",
+		LeaseDuration: time.Second,
+		RenewDeadline: 500 * time.Millisecond,
+		RetryPeriod:   100 * time.Millisecond,
+	})
+	return c
+}
+
+func leaseHolder(t *testing.T, kubeclientset *kubefake.Clientset) string {
+	t.Helper()
+	lease, err := kubeclientset.CoordinationV1().Leases("default").Get("kube-envoy-controller", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+func waitForLeader(t *testing.T, c *controller) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !c.elector.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatal("the lease was not acquired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestShutdownReleasesLeaseAfterWorkers(t *testing.T) {
+	kubeclientset := kubefake.NewSimpleClientset()
+	c := electedController(kubeclientset)
+	ctx, cancel := context.WithCancel(context.Background())
+	workersDone := make(chan struct{})
+	exited := make(chan int)
+	go func() { exited <- c.runLeaderElection(ctx, workersDone) }()
+	waitForLeader(t, c)
+
+	cancel()
+	time.Sleep(300 * time.Millisecond)
+	select {
+	case code := <-exited:
+		t.Fatalf("exited %d before the workers stopped", code)
+	default:
+	}
+	if holder := leaseHolder(t, kubeclientset); holder != "replica-a" {
+		t.Fatalf("lease held by %q while the workers drain, want replica-a", holder)
+	}
+	if !c.queue.ShuttingDown() {
+		t.Fatal("the queue was not shut down")
+	}
+
+	close(workersDone)
+	if code := <-exited; code != 0 {
+		t.Fatalf("exit code %d, want 0", code)
+	}
+	if holder := leaseHolder(t, kubeclientset); holder != "" {
+		t.Fatalf("lease still held by %q after shutdown", holder)
+	}
+}
+
+func TestLostLeaseExitsAtOnce(t *testing.T) {
+	kubeclientset := kubefake.NewSimpleClientset()
+	var failRenewals int32
+	kubeclientset.PrependReactor("update", "leases", func(action core.Action) (bool, runtime.Object, error) {
+		if atomic.LoadInt32(&failRenewals) == 1 {
+			return true, nil, fmt.Errorf("api server unreachable")
+		}
+		return false, nil, nil
+	})
+	c := electedController(kubeclientset)
+	// workers that never finish must not hold up a replica that lost its lease
+	workersDone := make(chan struct{})
+	exited := make(chan int)
+	go func() { exited <- c.runLeaderElection(context.Background(), workersDone) }()
+	waitForLeader(t, c)
+
+	atomic.StoreInt32(&failRenewals, 1)
+	select {
+	case code := <-exited:
+		if code != 1 {
+			t.Fatalf("exit code %d after losing the lease, want 1", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not exit after losing the lease")
+	}
+}
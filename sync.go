@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	envoyutils "github.com/starizard/kube-envoy-controller/pkg/envoy"
+	"github.com/starizard/kube-envoy-controller/pkg/logging"
+	"github.com/starizard/kube-envoy-controller/pkg/metrics"
+)
+
+// Current objects are read from the shared informer caches, which the owner handlers in main.go keep
+// watching, and copied before being changed.
+// The fields the controller owns are compared exactly, so a port, container or key added or removed
+// by hand counts as drift. The few of them the api server fills in when left empty are first copied
+// from the current object into the desired one; fields the controller does not own are left alone.
+
+// syncConfigMap creates the bootstrap config map or rewrites it when it no longer matches the spec
+func (c *controller) syncConfigMap(ctx context.Context, envoy *v1.Envoy) error {
+	cfgClient := c.kubeclientset.CoreV1().ConfigMaps(envoy.Namespace)
+	desired, err := envoyutils.ConfigMap(envoy)
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Debug("Rendered bootstrap", "bootstrap", desired.Data["envoy.yaml"])
+
+	current, err := c.kubeFactoryFor(envoy.Namespace).Core().V1().ConfigMaps().Lister().ConfigMaps(envoy.Namespace).Get(desired.Name)
+	if errors.IsNotFound(err) {
+		logging.FromContext(ctx).Info("Creating configmap", "configmap", desired.Name)
+		_, err = cfgClient.Create(desired)
+		c.recordChange(envoy, reasonCreated, "configmap", desired.Name, err)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	updated := current.DeepCopy()
+	adopted, err := claim(updated, envoy)
+	if err != nil {
+		return err
+	}
+	if !adopted && equality.Semantic.DeepEqual(desired.Data, current.Data) && len(current.BinaryData) == 0 {
+		return nil
+	}
+	updated.Data = desired.Data
+	updated.BinaryData = nil
+	logging.FromContext(ctx).Info("Updating configmap", "configmap", desired.Name)
+	_, err = cfgClient.Update(updated)
+	c.recordChange(envoy, reasonUpdated, "configmap", desired.Name, err)
+	if err == nil {
+		metrics.DriftCorrected("configmap")
+	}
+	return err
+}
+
+// syncDeployment creates the envoy deployment or puts its spec back into shape, and returns it
+func (c *controller) syncDeployment(ctx context.Context, envoy *v1.Envoy, listeners []*v1.EnvoyListener) (*appsv1.Deployment, error) {
+	deploymentsClient := c.kubeclientset.AppsV1().Deployments(envoy.Namespace)
+	desired, err := envoyutils.Deployment(envoy, listeners)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := c.kubeFactoryFor(envoy.Namespace).Apps().V1().Deployments().Lister().Deployments(envoy.Namespace).Get(desired.Name)
+	if errors.IsNotFound(err) {
+		logging.FromContext(ctx).Info("Creating deployment", "deployment", desired.Name)
+		created, err := deploymentsClient.Create(desired)
+		c.recordChange(envoy, reasonCreated, "deployment", desired.Name, err)
+		return created, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if current.DeletionTimestamp != nil {
+		// replaced for a new selector, it is created again once the orphaning delete went through
+		return current, nil
+	}
+	updated := current.DeepCopy()
+	adopted, err := claim(updated, envoy)
+	if err != nil {
+		return nil, err
+	}
+	if !equality.Semantic.DeepEqual(desired.Spec.Selector, current.Spec.Selector) {
+		return current, c.replaceDeployment(ctx, envoy, updated, desired.Spec.Selector)
+	}
+	if desired.Spec.Replicas == nil {
+		// without replicas on the envoy the deployment keeps whatever it was scaled to
+		desired.Spec.Replicas = current.Spec.Replicas
+	}
+	defaultPodSpec(&desired.Spec.Template.Spec, &current.Spec.Template.Spec)
+	if !adopted && !deploymentDrifted(desired, current) {
+		return current, nil
+	}
+	updated.Spec.Replicas = desired.Spec.Replicas
+	updated.Spec.Strategy = desired.Spec.Strategy
+	updated.Spec.Template.Labels = desired.Spec.Template.Labels
+	updated.Spec.Template.Annotations = desired.Spec.Template.Annotations
+	updated.Spec.Template.Spec.Containers = desired.Spec.Template.Spec.Containers
+	updated.Spec.Template.Spec.Volumes = desired.Spec.Template.Spec.Volumes
+	updated.Spec.Template.Spec.NodeSelector = desired.Spec.Template.Spec.NodeSelector
+	updated.Spec.Template.Spec.Tolerations = desired.Spec.Template.Spec.Tolerations
+	logging.FromContext(ctx).Info("Updating deployment", "deployment", desired.Name)
+	current, err = deploymentsClient.Update(updated)
+	c.recordChange(envoy, reasonUpdated, "deployment", desired.Name, err)
+	if err == nil {
+		metrics.DriftCorrected("deployment")
+	}
+	return current, err
+}
+
+// replaceDeployment prepares the deployment of envoy for a new selector, which the api server does
+// not allow changing. The replica sets of the deployment and their pods get the labels of the new
+// selector, and the deployment is deleted leaving them running. The next sync creates it again with
+// the new selector; it adopts the relabelled replica sets and rolls their pods like any template
+// change, while the service, which already selects them, keeps its endpoints throughout.
+func (c *controller) replaceDeployment(ctx context.Context, envoy *v1.Envoy, current *appsv1.Deployment, selector *metav1.LabelSelector) error {
+	rsClient := c.kubeclientset.AppsV1().ReplicaSets(current.Namespace)
+	podsClient := c.kubeclientset.CoreV1().Pods(current.Namespace)
+
+	replicaSets, err := rsClient.List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		if !metav1.IsControlledBy(rs, current) {
+			continue
+		}
+		pods, err := podsClient.List(metav1.ListOptions{LabelSelector: metav1.FormatLabelSelector(rs.Spec.Selector)})
+		if err != nil {
+			return err
+		}
+		for j := range pods.Items {
+			pod := &pods.Items[j]
+			if !metav1.IsControlledBy(pod, rs) || hasLabels(pod.Labels, selector.MatchLabels) {
+				continue
+			}
+			pod.Labels = withLabels(pod.Labels, selector.MatchLabels)
+			if _, err := podsClient.Update(pod); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		if !hasLabels(rs.Labels, selector.MatchLabels) {
+			rs.Labels = withLabels(rs.Labels, selector.MatchLabels)
+			if _, err := rsClient.Update(rs); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	logging.FromContext(ctx).Info("Replacing deployment for a new selector", "deployment", current.Name)
+	orphan := metav1.DeletePropagationOrphan
+	err = c.kubeclientset.AppsV1().Deployments(current.Namespace).Delete(current.Name, &metav1.DeleteOptions{
+		PropagationPolicy: &orphan,
+		Preconditions:     &metav1.Preconditions{UID: &current.UID},
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	c.recordEvent(envoy, apiv1.EventTypeNormal, reasonDeleted, "Deleted deployment %s leaving its pods running, it is created again with a new selector", current.Name)
+	return nil
+}
+
+func hasLabels(set, want map[string]string) bool {
+	for k, v := range want {
+		if set[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func withLabels(set, add map[string]string) map[string]string {
+	out := map[string]string{}
+	for k, v := range set {
+		out[k] = v
+	}
+	for k, v := range add {
+		out[k] = v
+	}
+	return out
+}
+
+// syncService creates the envoy service or puts its type, ports and selector back into shape, and returns it
+func (c *controller) syncService(ctx context.Context, envoy *v1.Envoy, listeners []*v1.EnvoyListener) (*apiv1.Service, error) {
+	svcClient := c.kubeclientset.CoreV1().Services(envoy.Namespace)
+	desired := envoyutils.Service(envoy, listeners)
+
+	current, err := c.kubeFactoryFor(envoy.Namespace).Core().V1().Services().Lister().Services(envoy.Namespace).Get(desired.Name)
+	if errors.IsNotFound(err) {
+		logging.FromContext(ctx).Info("Creating service", "service", desired.Name)
+		created, err := svcClient.Create(desired)
+		c.recordChange(envoy, reasonCreated, "service", desired.Name, err)
+		return created, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	updated := current.DeepCopy()
+	adopted, err := claim(updated, envoy)
+	if err != nil {
+		return nil, err
+	}
+	defaultServicePorts(desired, current)
+	if !adopted && !serviceDrifted(desired, current) {
+		return current, nil
+	}
+	// keep the allocated cluster ip, drop what only node port and load balancer services may have
+	updated.Spec.Type = desired.Spec.Type
+	updated.Spec.Ports = desired.Spec.Ports
+	updated.Spec.Selector = desired.Spec.Selector
+	updated.Spec.ExternalTrafficPolicy = ""
+	updated.Spec.HealthCheckNodePort = 0
+	logging.FromContext(ctx).Info("Updating service", "service", desired.Name)
+	current, err = svcClient.Update(updated)
+	c.recordChange(envoy, reasonUpdated, "service", desired.Name, err)
+	if err == nil {
+		metrics.DriftCorrected("service")
+	}
+	return current, err
+}
+
+// deploymentDrifted reports whether the fields of current the controller owns differ from desired
+func deploymentDrifted(desired, current *appsv1.Deployment) bool {
+	d, c := desired.Spec, current.Spec
+	return !equality.Semantic.DeepEqual(d.Replicas, c.Replicas) ||
+		!equality.Semantic.DeepEqual(d.Strategy, c.Strategy) ||
+		!equality.Semantic.DeepEqual(d.Template.Labels, c.Template.Labels) ||
+		!equality.Semantic.DeepEqual(d.Template.Annotations, c.Template.Annotations) ||
+		!equality.Semantic.DeepEqual(d.Template.Spec.Containers, c.Template.Spec.Containers) ||
+		!equality.Semantic.DeepEqual(d.Template.Spec.Volumes, c.Template.Spec.Volumes) ||
+		!equality.Semantic.DeepEqual(d.Template.Spec.NodeSelector, c.Template.Spec.NodeSelector) ||
+		!equality.Semantic.DeepEqual(d.Template.Spec.Tolerations, c.Template.Spec.Tolerations)
+}
+
+// defaultPodSpec copies into desired the container and volume fields the api server defaulted in
+// current, matching containers, ports and volumes by name
+func defaultPodSpec(desired, current *apiv1.PodSpec) {
+	for i := range desired.Containers {
+		d := &desired.Containers[i]
+		for _, c := range current.Containers {
+			if c.Name != d.Name {
+				continue
+			}
+			if d.ImagePullPolicy == "" {
+				d.ImagePullPolicy = c.ImagePullPolicy
+			}
+			if d.TerminationMessagePath == "" {
+				d.TerminationMessagePath = c.TerminationMessagePath
+			}
+			if d.TerminationMessagePolicy == "" {
+				d.TerminationMessagePolicy = c.TerminationMessagePolicy
+			}
+			// requests left out default to the limits
+			for name, limit := range d.Resources.Limits {
+				if _, ok := d.Resources.Requests[name]; ok {
+					continue
+				}
+				if request, ok := c.Resources.Requests[name]; ok && request.Cmp(limit) == 0 {
+					if d.Resources.Requests == nil {
+						d.Resources.Requests = apiv1.ResourceList{}
+					}
+					d.Resources.Requests[name] = request
+				}
+			}
+			for j := range d.Ports {
+				for _, p := range c.Ports {
+					if p.Name == d.Ports[j].Name && d.Ports[j].Protocol == "" {
+						d.Ports[j].Protocol = p.Protocol
+					}
+				}
+			}
+			defaultProbe(d.LivenessProbe, c.LivenessProbe)
+			defaultProbe(d.ReadinessProbe, c.ReadinessProbe)
+		}
+	}
+	for i := range desired.Volumes {
+		d := &desired.Volumes[i]
+		for _, c := range current.Volumes {
+			if c.Name == d.Name && d.ConfigMap != nil && c.ConfigMap != nil && d.ConfigMap.DefaultMode == nil {
+				d.ConfigMap.DefaultMode = c.ConfigMap.DefaultMode
+			}
+		}
+	}
+}
+
+// defaultProbe copies into desired the timings and scheme the api server defaulted in current
+func defaultProbe(desired, current *apiv1.Probe) {
+	if desired == nil || current == nil {
+		return
+	}
+	if desired.TimeoutSeconds == 0 {
+		desired.TimeoutSeconds = current.TimeoutSeconds
+	}
+	if desired.PeriodSeconds == 0 {
+		desired.PeriodSeconds = current.PeriodSeconds
+	}
+	if desired.SuccessThreshold == 0 {
+		desired.SuccessThreshold = current.SuccessThreshold
+	}
+	if desired.FailureThreshold == 0 {
+		desired.FailureThreshold = current.FailureThreshold
+	}
+	if desired.HTTPGet != nil && current.HTTPGet != nil && desired.HTTPGet.Scheme == "" {
+		desired.HTTPGet.Scheme = current.HTTPGet.Scheme
+	}
+}
+
+// serviceDrifted reports whether the type, ports or selector of current differ from desired
+func serviceDrifted(desired, current *apiv1.Service) bool {
+	return desired.Spec.Type != current.Spec.Type ||
+		!equality.Semantic.DeepEqual(desired.Spec.Ports, current.Spec.Ports) ||
+		!equality.Semantic.DeepEqual(desired.Spec.Selector, current.Spec.Selector)
+}
+
+// defaultServicePorts copies into desired the node ports current was allocated, as long as it keeps its type
+func defaultServicePorts(desired, current *apiv1.Service) {
+	if desired.Spec.Type != current.Spec.Type {
+		return
+	}
+	ports := desired.Spec.Ports
+	for i := range ports {
+		for _, p := range current.Spec.Ports {
+			if p.Name == ports[i].Name && ports[i].NodePort == 0 && p.NodePort != 0 {
+				ports[i].NodePort = p.NodePort
+			}
+		}
+	}
+}
+
+// claim makes envoy the controller of obj if nothing controls it yet, e.g. objects created before
+// envoys owned them or orphaned by a previous envoy. It fails if another object controls obj.
+func claim(obj metav1.Object, envoy *v1.Envoy) (bool, error) {
+	ref := metav1.GetControllerOf(obj)
+	if ref == nil {
+		obj.SetOwnerReferences(append(obj.GetOwnerReferences(), envoyutils.OwnerReferences(envoy)...))
+		return true, nil
+	}
+	if ref.UID != envoy.UID {
+		return false, fmt.Errorf("%s is already controlled by %s %s", obj.GetName(), ref.Kind, ref.Name)
+	}
+	return false, nil
+}
+
+// pruneRenamed deletes the objects envoy generated under a name its spec no longer uses
+func (c *controller) pruneRenamed(ctx context.Context, envoy *v1.Envoy) error {
+	background := metav1.DeletePropagationBackground
+	options := &metav1.DeleteOptions{PropagationPolicy: &background}
+
+	deployments, err := c.kubeFactoryFor(envoy.Namespace).Apps().V1().Deployments().Lister().Deployments(envoy.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, d := range deployments {
+		if d.Name != envoy.Spec.Name && envoyutils.IsOwnedBy(d, envoy) {
+			logging.FromContext(ctx).Info("Deleting renamed deployment", "deployment", d.Name)
+			if err := c.kubeclientset.AppsV1().Deployments(d.Namespace).Delete(d.Name, options); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			c.recordChange(envoy, reasonDeleted, "renamed deployment", d.Name, nil)
+		}
+	}
+
+	services, err := c.kubeFactoryFor(envoy.Namespace).Core().V1().Services().Lister().Services(envoy.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, svc := range services {
+		if svc.Name != envoy.Spec.Name && envoyutils.IsOwnedBy(svc, envoy) {
+			logging.FromContext(ctx).Info("Deleting renamed service", "service", svc.Name)
+			if err := c.kubeclientset.CoreV1().Services(svc.Namespace).Delete(svc.Name, options); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			c.recordChange(envoy, reasonDeleted, "renamed service", svc.Name, nil)
+		}
+	}
+
+	cfgs, err := c.kubeFactoryFor(envoy.Namespace).Core().V1().ConfigMaps().Lister().ConfigMaps(envoy.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, cfg := range cfgs {
+		if cfg.Name != envoy.Spec.ConfigMapName && envoyutils.IsOwnedBy(cfg, envoy) {
+			logging.FromContext(ctx).Info("Deleting renamed configmap", "configmap", cfg.Name)
+			if err := c.kubeclientset.CoreV1().ConfigMaps(cfg.Namespace).Delete(cfg.Name, options); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			c.recordChange(envoy, reasonDeleted, "renamed configmap", cfg.Name, nil)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/starizard/kube-envoy-controller/pkg/api/envoy.starizard.io/v1"
+	envoyutils "github.com/starizard/kube-envoy-controller/pkg/envoy"
+)
+
+func driftEnvoy() *v1.Envoy {
+	return &v1.Envoy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "edge", UID: "edge-uid"},
+		Spec: v1.EnvoySpec{
+			Name:          "edge",
+			ConfigMapName: "edge",
+			XDS:           v1.EnvoyXDS{BuiltIn: true},
+			Resources: &apiv1.ResourceRequirements{
+				Limits: apiv1.ResourceList{apiv1.ResourceMemory: resource.MustParse("256Mi")},
+			},
+			NodeSelector: map[string]string{"edge": "true"},
+		},
+	}
+}
+
+// serverDefaulted returns d as the api server stores it, with the fields it defaults filled in
+func serverDefaulted(d *appsv1.Deployment) *appsv1.Deployment {
+	d = d.DeepCopy()
+	replicas := int32(1)
+	d.Spec.Replicas = &replicas
+	pod := &d.Spec.Template.Spec
+	pod.RestartPolicy = apiv1.RestartPolicyAlways
+	pod.DNSPolicy = apiv1.DNSClusterFirst
+	for i := range pod.Containers {
+		pod.Containers[i].ImagePullPolicy = apiv1.PullIfNotPresent
+		pod.Containers[i].TerminationMessagePath = apiv1.TerminationMessagePathDefault
+		pod.Containers[i].TerminationMessagePolicy = apiv1.TerminationMessageReadFile
+		if limits := pod.Containers[i].Resources.Limits; limits != nil && pod.Containers[i].Resources.Requests == nil {
+			pod.Containers[i].Resources.Requests = limits.DeepCopy()
+		}
+	}
+	mode := int32(0644)
+	for i := range pod.Volumes {
+		pod.Volumes[i].ConfigMap.DefaultMode = &mode
+	}
+	return d
+}
+
+func TestDeploymentDrift(t *testing.T) {
+	tests := []struct {
+		name  string
+		edit  func(d *appsv1.Deployment)
+		drift bool
+	}{
+		{name: "server defaults", edit: func(d *appsv1.Deployment) {}},
+		{name: "scaled", edit: func(d *appsv1.Deployment) { *d.Spec.Replicas = 3 }},
+		{name: "removed port", drift: true, edit: func(d *appsv1.Deployment) {
+			d.Spec.Template.Spec.Containers[0].Ports = nil
+		}},
+		{name: "extra container", drift: true, edit: func(d *appsv1.Deployment) {
+			d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers, apiv1.Container{Name: "debug"})
+		}},
+		{name: "extra env", drift: true, edit: func(d *appsv1.Deployment) {
+			d.Spec.Template.Spec.Containers[0].Env = []apiv1.EnvVar{{Name: "DEBUG", Value: "1"}}
+		}},
+		{name: "cleared strategy", drift: true, edit: func(d *appsv1.Deployment) {
+			d.Spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+		}},
+		{name: "template annotation", drift: true, edit: func(d *appsv1.Deployment) {
+			d.Spec.Template.Annotations["debug"] = "true"
+		}},
+		{name: "changed requests", drift: true, edit: func(d *appsv1.Deployment) {
+			d.Spec.Template.Spec.Containers[0].Resources.Requests[apiv1.ResourceMemory] = resource.MustParse("128Mi")
+		}},
+		{name: "changed node selector", drift: true, edit: func(d *appsv1.Deployment) {
+			d.Spec.Template.Spec.NodeSelector = nil
+		}},
+		{name: "added toleration", drift: true, edit: func(d *appsv1.Deployment) {
+			d.Spec.Template.Spec.Tolerations = []apiv1.Toleration{{Operator: apiv1.TolerationOpExists}}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envoy := driftEnvoy()
+			desired, err := envoyutils.Deployment(envoy, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			current := serverDefaulted(desired)
+			tt.edit(current)
+
+			desired.Spec.Replicas = current.Spec.Replicas
+			defaultPodSpec(&desired.Spec.Template.Spec, &current.Spec.Template.Spec)
+			if got := deploymentDrifted(desired, current); got != tt.drift {
+				t.Fatalf("drifted = %v, want %v", got, tt.drift)
+			}
+		})
+	}
+}
+
+func TestServiceDrift(t *testing.T) {
+	envoy := driftEnvoy()
+	current := envoyutils.Service(envoy, nil)
+	current.Spec.ClusterIP = "10.0.0.1"
+	current.Spec.SessionAffinity = apiv1.ServiceAffinityNone
+
+	desired := envoyutils.Service(envoy, nil)
+	defaultServicePorts(desired, current)
+	if serviceDrifted(desired, current) {
+		t.Fatal("server defaults count as drift")
+	}
+
+	current.Spec.Ports = append(current.Spec.Ports, apiv1.ServicePort{Name: "admin", Port: 15000})
+	if !serviceDrifted(desired, current) {
+		t.Fatal("an extra port does not count as drift")
+	}
+}